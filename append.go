@@ -0,0 +1,262 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// minPartSize is the minimum size of every part of a multipart upload but the last
+	minPartSize = 5 << 20
+	// appendPartSize is the size of the parts uploaded by AppendWriter
+	appendPartSize = 8 << 20
+)
+
+// ErrWriterClosed is returned when writing to a closed AppendWriter
+var ErrWriterClosed = errors.New("s3fs: writer closed")
+
+// AppendWriter appends to an object. See FileSystem.OpenAppend
+type AppendWriter struct {
+	fs       FileSystem
+	ctx      context.Context
+	key      string
+	uploadID *string
+	head     *s3.HeadObjectOutput
+	parts    []*s3.CompletedPart
+	buf      bytes.Buffer
+	sealer   *chunkSealer
+	err      error
+	closed   bool
+	// source holds the existing data of the object, the content of pointer objects
+	source string
+}
+
+// OpenAppend returns a writer that appends to the object with the name, or creates it if it
+// doesn't exist. S3 has no append, so the writer composes a new version of the object with a
+// multipart upload: the existing object becomes the first parts with UploadPartCopy and the
+// written data is uploaded as the next parts. Existing objects smaller than the 5MB minimum
+// part size are downloaded and uploaded again instead. The appended data becomes visible when
+// Close completes the upload. Copying fails if the object changed since OpenAppend, but S3 can't
+// make the same check when a small object is rewritten, so concurrent appends can be lost.
+// Appending to an object stored by WithDeduplication appends to its content and replaces the
+// pointer with a regular object.
+func (f FileSystem) OpenAppend(name string) (*AppendWriter, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	w := &AppendWriter{fs: f, ctx: ctx, key: key}
+
+	source, head, err := f.headContent(ctx, key)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	w.source = source
+	if f.encryption != nil || (head != nil && isEncrypted(head.Metadata)) {
+		return nil, ErrEncryptedAppend
+	}
+	w.head = head
+
+	w.uploadID, err = f.createMultipartUpload(ctx, key, head)
+	if err != nil {
+		return nil, err
+	}
+
+	if head != nil {
+		if err := w.start(); err != nil {
+			f.abortMultipartUpload(ctx, key, w.uploadID)
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// Create returns a writer that replaces the object with the name, or creates it, with the
+// data written to it. Like with OpenAppend the data is uploaded in parts as it is written,
+// so it doesn't have to fit in memory, and the object is only replaced when Close completes
+// the upload. The content type is guessed from the extension of the name. With WithEncryption
+// the data is encrypted as it is written.
+func (f FileSystem) Create(name string) (*AppendWriter, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	w := &AppendWriter{fs: f, ctx: ctx, key: key}
+
+	// head only carries the metadata of the new object
+	w.head = &s3.HeadObjectOutput{}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		w.head.ContentType = aws.String(contentType)
+	}
+
+	if f.encryption != nil {
+		enc, metadata, err := f.newObjectCipher(ctx)
+		if err != nil {
+			return nil, err
+		}
+		w.sealer = &chunkSealer{c: enc}
+		w.head.Metadata = metadata
+	}
+
+	w.uploadID, err = f.createMultipartUpload(ctx, key, w.head)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// start adds the existing object to the upload
+func (w *AppendWriter) start() error {
+	size := aws.Int64Value(w.head.ContentLength)
+	if size >= minPartSize {
+		parts, err := w.fs.copyParts(w.ctx, w.source, w.key, w.uploadID, w.head)
+		w.parts = parts
+		return err
+	}
+
+	if size == 0 {
+		return nil
+	}
+
+	object, err := w.fs.getObject(w.ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(w.fs.bucket),
+		Key:     aws.String(w.source),
+		IfMatch: w.head.ETag,
+	})
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	_, err = w.buf.ReadFrom(object.Body)
+	return err
+}
+
+// Write buffers p and uploads a part whenever the buffer is full
+func (w *AppendWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n := len(p)
+	if w.sealer != nil {
+		p = w.sealer.write(p)
+	}
+
+	w.buf.Write(p)
+	for w.buf.Len() >= appendPartSize {
+		if w.err = w.uploadPart(w.buf.Next(appendPartSize)); w.err != nil {
+			return n, w.err
+		}
+	}
+
+	return n, nil
+}
+
+func (w *AppendWriter) uploadPart(data []byte) error {
+	number := int64(len(w.parts) + 1)
+
+	start := time.Now()
+	part, err := w.fs.s3.UploadPartWithContext(w.ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(w.fs.bucket),
+		Key:        aws.String(w.key),
+		UploadId:   w.uploadID,
+		PartNumber: aws.Int64(number),
+		Body:       bytes.NewReader(data),
+	})
+	w.fs.requestDone(w.ctx, "UploadPart", start, err, "key", w.key, "part", number, "bytes", len(data))
+	if err != nil {
+		return toFSError(err)
+	}
+
+	w.parts = append(w.parts, &s3.CompletedPart{
+		ETag:       part.ETag,
+		PartNumber: aws.Int64(number),
+	})
+	return nil
+}
+
+// Close uploads the remaining data and completes the upload. If writing failed, Close
+// aborts the upload and returns the error, leaving the object unchanged.
+func (w *AppendWriter) Close() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	w.closed = true
+
+	if w.err != nil {
+		w.fs.abortMultipartUpload(w.ctx, w.key, w.uploadID)
+		return w.err
+	}
+
+	if w.sealer != nil {
+		w.buf.Write(w.sealer.close())
+	}
+
+	if len(w.parts) == 0 {
+		// the whole object fits in a single request, so there's no need for the upload
+		w.fs.abortMultipartUpload(w.ctx, w.key, w.uploadID)
+		return w.putBuffer()
+	}
+
+	if w.buf.Len() > 0 {
+		if err := w.uploadPart(w.buf.Bytes()); err != nil {
+			w.fs.abortMultipartUpload(w.ctx, w.key, w.uploadID)
+			return err
+		}
+	}
+
+	return w.fs.completeMultipartUpload(w.ctx, w.key, w.uploadID, w.parts)
+}
+
+// Abort aborts the upload, leaving the object unchanged. Abort does nothing after Close.
+func (w *AppendWriter) Abort() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+
+	w.fs.abortMultipartUpload(w.ctx, w.key, w.uploadID)
+}
+
+// putBuffer stores the buffer as the whole object with PutObject, keeping the metadata of the
+// existing object
+func (w *AppendWriter) putBuffer() error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(w.fs.bucket),
+		Key:    aws.String(w.key),
+		Body:   bytes.NewReader(w.buf.Bytes()),
+	}
+	if w.head != nil {
+		input.CacheControl = w.head.CacheControl
+		input.ContentDisposition = w.head.ContentDisposition
+		input.ContentEncoding = w.head.ContentEncoding
+		input.ContentLanguage = w.head.ContentLanguage
+		input.ContentType = w.head.ContentType
+		input.Metadata = w.head.Metadata
+		input.StorageClass = w.head.StorageClass
+	}
+
+	_, err := w.fs.putObject(w.ctx, input)
+	return err
+}
+
+var _ io.WriteCloser = (*AppendWriter)(nil)
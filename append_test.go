@@ -0,0 +1,113 @@
+package s3fs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv)
+
+	// larger than appendPartSize, so it is uploaded in two parts
+	data := bytes.Repeat([]byte("0123456789"), 1<<20)
+	w, err := s3Fs.Create("notes.txt")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if got, _ := srv.Object("public-sample-data", "notes.txt"); !bytes.Equal(got, data) {
+		t.Fatalf("error: stored %d bytes, want %d", len(got), len(data))
+	}
+
+	w, _ = s3Fs.Create("small.txt")
+	io.WriteString(w, "small")
+	if err := w.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if got, _ := srv.Object("public-sample-data", "small.txt"); string(got) != "small" {
+		t.Fatalf("error: stored %q", got)
+	}
+	if err := w.Close(); err != ErrWriterClosed {
+		t.Fatalf("error: expected ErrWriterClosed, got %v", err)
+	}
+
+	w, _ = s3Fs.Create("aborted.txt")
+	io.WriteString(w, "aborted")
+	w.Abort()
+	if _, ok := srv.Object("public-sample-data", "aborted.txt"); ok {
+		t.Fatalf("error: expected Abort to leave no object")
+	}
+}
+
+func TestOpenAppend(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv)
+
+	// missing and small objects are rewritten with PutObject
+	for _, s := range []string{"first ", "second"} {
+		w, err := s3Fs.OpenAppend("log.txt")
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		io.WriteString(w, s)
+		if err := w.Close(); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+	if got, _ := srv.Object("public-sample-data", "log.txt"); string(got) != "first second" {
+		t.Fatalf("error: stored %q", got)
+	}
+}
+
+func TestOpenAppendLarge(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv)
+
+	// over the minimum part size, so it is copied with UploadPartCopy
+	existing := bytes.Repeat([]byte("e"), 6<<20)
+	srv.PutObject("public-sample-data", "large.log", existing, "text/plain")
+
+	w, err := s3Fs.OpenAppend("large.log")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	io.WriteString(w, "appended")
+	if err := w.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	got, _ := srv.Object("public-sample-data", "large.log")
+	if !bytes.Equal(got, append(existing, "appended"...)) {
+		t.Fatalf("error: stored %d bytes, want %d", len(got), len(existing)+len("appended"))
+	}
+}
+
+func TestCopyRanges(t *testing.T) {
+	for _, size := range []int64{6 << 20, copyPartSize, copyPartSize + 1<<20, 3*copyPartSize + minPartSize - 1, 3*copyPartSize + minPartSize} {
+		ranges := copyRanges(size)
+		next := int64(0)
+		for i, r := range ranges {
+			if r[0] != next || r[1] < r[0] {
+				t.Fatalf("error: size %d: range %d is %v after %d", size, i, r, next)
+			}
+			if r[1]-r[0]+1 < minPartSize {
+				t.Fatalf("error: size %d: range %d has %d bytes, under the minimum part size", size, i, r[1]-r[0]+1)
+			}
+			next = r[1] + 1
+		}
+		if next != size {
+			t.Fatalf("error: size %d: ranges end at %d", size, next)
+		}
+	}
+
+	if ranges := copyRanges(copyPartSize + 1<<20); len(ranges) != 1 {
+		t.Fatalf("error: expected the 1MB remainder in the last part, got %v", ranges)
+	}
+}
@@ -0,0 +1,135 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrArchived is returned when opening an object in the GLACIER or DEEP_ARCHIVE storage class,
+// or in an archive tier of INTELLIGENT_TIERING, that hasn't been restored. See Restore
+var ErrArchived = errors.New("s3fs: object is archived")
+
+// RestoreTier is the speed, and cost, of a restore
+type RestoreTier string
+
+// The tiers of Restore, from the fastest to the cheapest
+const (
+	RestoreExpedited RestoreTier = s3.TierExpedited
+	RestoreStandard  RestoreTier = s3.TierStandard
+	RestoreBulk      RestoreTier = s3.TierBulk
+)
+
+// RestoreStatus is the state of the restore of an archived object. See FileSystem.RestoreStatus
+type RestoreStatus struct {
+	StorageClass string
+	// Archived is set for objects whose data has to be restored to be read
+	Archived bool
+	// InProgress is set while a restore is running
+	InProgress bool
+	// Restored is set when a restored copy can be read, until Expiry
+	Restored bool
+	Expiry   time.Time
+}
+
+// archivedClasses are the storage classes whose objects can't be read without a restore
+var archivedClasses = map[string]bool{
+	s3.StorageClassGlacier:     true,
+	s3.StorageClassDeepArchive: true,
+}
+
+// StorageClass returns the storage class of the object, "STANDARD" if S3 didn't report one,
+// or "" for directories
+func (f fileStat) StorageClass() string {
+	if f.isDir {
+		return ""
+	}
+	if f.storageClass == "" {
+		return s3.StorageClassStandard
+	}
+	return f.storageClass
+}
+
+// Restore starts restoring a temporary copy of the archived object with the name for days
+// days, after which Open can read it. Restores take minutes to hours depending on tier;
+// RestoreStatus reports when they are done. Calling Restore while a restore is in progress
+// does nothing, and calling it for a restored object extends its expiry.
+func (f FileSystem) Restore(name string, tier RestoreTier, days int64) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	_, err = f.s3.RestoreObjectWithContext(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+		RestoreRequest: &s3.RestoreRequest{
+			Days:                 aws.Int64(days),
+			GlacierJobParameters: &s3.GlacierJobParameters{Tier: aws.String(string(tier))},
+		},
+	})
+	f.requestDone(ctx, "RestoreObject", start, err, "key", key, "tier", tier)
+
+	if aerr, ok := err.(awserr.RequestFailure); ok && aerr.Code() == "RestoreAlreadyInProgress" {
+		return nil
+	}
+	return toFSError(err)
+}
+
+// RestoreStatus returns the storage class and the state of the restore of the object with the
+// name, from a HeadObject
+func (f FileSystem) RestoreStatus(name string) (RestoreStatus, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return RestoreStatus{}, err
+	}
+
+	head, err := f.headObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return RestoreStatus{}, err
+	}
+
+	status := parseRestore(aws.StringValue(head.Restore))
+	status.StorageClass = aws.StringValue(head.StorageClass)
+	if status.StorageClass == "" {
+		status.StorageClass = s3.StorageClassStandard
+	}
+	status.Archived = archivedClasses[status.StorageClass] ||
+		aws.StringValue(head.ArchiveStatus) != ""
+	return status, nil
+}
+
+// parseRestore parses the x-amz-restore header, such as
+// `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`
+func parseRestore(header string) RestoreStatus {
+	var status RestoreStatus
+	if header == "" {
+		return status
+	}
+
+	for _, field := range strings.Split(header, `",`) {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		value = strings.Trim(value, `"`)
+
+		switch name {
+		case "ongoing-request":
+			status.InProgress = value == "true"
+			status.Restored = value == "false"
+		case "expiry-date":
+			status.Expiry, _ = http.ParseTime(value)
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,40 @@
+package s3fs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRestore(t *testing.T) {
+	cases := map[string]RestoreStatus{
+		"":                       {},
+		`ongoing-request="true"`: {InProgress: true},
+		`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`: {
+			Restored: true,
+			Expiry:   time.Date(2012, 12, 21, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for header, want := range cases {
+		if got := parseRestore(header); got != want {
+			t.Fatalf("error: parsed %q as %+v, want %+v", header, got, want)
+		}
+	}
+}
+
+func TestStorageClass(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t))
+
+	fi, err := s3Fs.Stat("passengers.txt")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if class := fi.(interface{ StorageClass() string }).StorageClass(); class != "STANDARD" {
+		t.Fatalf("error: expected STANDARD, got %s", class)
+	}
+
+	status, err := s3Fs.RestoreStatus("passengers.txt")
+	if err != nil || status.Archived || status.StorageClass != "STANDARD" {
+		t.Fatalf("error: unexpected status %+v, %v", status, err)
+	}
+}
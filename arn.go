@@ -0,0 +1,37 @@
+package s3fs
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+)
+
+// bucketARN is the access point or Object Lambda access point ARN passed to New instead of a
+// bucket name
+type bucketARN struct {
+	arn.ARN
+}
+
+// parseBucketARN returns the ARN of bucket if it is an access point or Object Lambda access
+// point ARN such as "arn:aws:s3-object-lambda:us-west-2:123456789012:accesspoint/thumbnails"
+func parseBucketARN(bucket string) (bucketARN, bool) {
+	if !arn.IsARN(bucket) {
+		return bucketARN{}, false
+	}
+
+	a, err := arn.Parse(bucket)
+	if err != nil || (a.Service != "s3" && a.Service != "s3-object-lambda") {
+		return bucketARN{}, false
+	}
+	if !strings.HasPrefix(a.Resource, "accesspoint/") && !strings.HasPrefix(a.Resource, "accesspoint:") {
+		return bucketARN{}, false
+	}
+
+	return bucketARN{a}, true
+}
+
+// objectLambda reports whether the ARN is of an Object Lambda access point, which
+// only supports reading
+func (a bucketARN) objectLambda() bool {
+	return a.Service == "s3-object-lambda"
+}
@@ -0,0 +1,46 @@
+package s3fs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestParseBucketARN(t *testing.T) {
+	cases := map[string]bool{
+		"public-sample-data": false,
+		"arn:aws:s3:us-west-2:123456789012:accesspoint/assets":               true,
+		"arn:aws:s3-object-lambda:eu-west-1:123456789012:accesspoint/thumbs": true,
+		"arn:aws:s3:::public-sample-data":                                    false,
+		"arn:aws:sqs:us-east-1:123456789012:queue":                           false,
+	}
+
+	for bucket, want := range cases {
+		if _, ok := parseBucketARN(bucket); ok != want {
+			t.Fatalf("error: parseBucketARN(%s) is %v, want %v", bucket, ok, want)
+		}
+	}
+}
+
+func TestBucketARNEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"arn:aws:s3:us-west-2:123456789012:accesspoint/assets":               "assets-123456789012.s3-accesspoint.us-west-2.amazonaws.com",
+		"arn:aws:s3-object-lambda:eu-west-1:123456789012:accesspoint/thumbs": "thumbs-123456789012.s3-object-lambda.eu-west-1.amazonaws.com",
+	}
+
+	for bucket, want := range cases {
+		s3Fs := New(bucket, "us-east-1", WithAnonymousCredentials())
+		req, _ := s3Fs.s3.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String("passengers.txt"),
+		})
+		if err := req.Build(); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		if host := req.HTTPRequest.URL.Host; host != want {
+			t.Fatalf("error: request for %s sent to %s, want %s", bucket, host, want)
+		}
+	}
+}
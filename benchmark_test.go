@@ -0,0 +1,201 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// The benchmarks run against the S3-compatible server at S3FS_BENCH_ENDPOINT, such as the
+// MinIO of testdata/docker-compose.bench.yml, and are skipped without it. The objects are
+// created in the bucket S3FS_BENCH_BUCKET ("s3fs-bench") on the first run. Sizes are tuned
+// with S3FS_BENCH_SIZE (bytes of the large object, 64MB), S3FS_BENCH_RANGE (bytes of every
+// ranged read, 64KB), S3FS_BENCH_CONCURRENCY (parallel ranges, 8) and S3FS_BENCH_KEYS
+// (objects under the listed prefix, 5000).
+
+var (
+	benchOnce sync.Once
+	benchFS   *FileSystem
+	benchErr  error
+)
+
+func benchEnv(b *testing.B, name string, def int64) int64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		b.Fatalf("error: %s: %v", name, err)
+	}
+	return n
+}
+
+// benchFileSystem returns the FileSystem of the benchmark bucket, creating its objects once
+func benchFileSystem(b *testing.B) *FileSystem {
+	endpoint := os.Getenv("S3FS_BENCH_ENDPOINT")
+	if endpoint == "" {
+		b.Skip("S3FS_BENCH_ENDPOINT isn't set")
+	}
+
+	bucket := os.Getenv("S3FS_BENCH_BUCKET")
+	if bucket == "" {
+		bucket = "s3fs-bench"
+	}
+
+	size := benchEnv(b, "S3FS_BENCH_SIZE", 64<<20)
+	keys := benchEnv(b, "S3FS_BENCH_KEYS", 5000)
+
+	benchOnce.Do(func() {
+		fsys := New(bucket, "us-east-1", WithEndpoint(endpoint), WithKeyMapper(PathKeyMapper))
+		benchErr = benchSetup(fsys, size, keys)
+		benchFS = fsys
+	})
+	if benchErr != nil {
+		b.Fatalf("error: setting up the benchmark bucket: %v", benchErr)
+	}
+
+	return benchFS
+}
+
+// benchSetup creates the bucket, the large object and the keys of the listed prefix unless
+// they already exist with the requested sizes
+func benchSetup(f *FileSystem, size, keys int64) error {
+	ctx := context.Background()
+
+	if _, err := f.s3.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(f.bucket)}); err != nil {
+		if _, err := f.s3.CreateBucketWithContext(ctx, &s3.CreateBucketInput{Bucket: aws.String(f.bucket)}); err != nil {
+			return err
+		}
+	}
+
+	large := fmt.Sprintf("large-%d.bin", size)
+	if info, err := f.Stat(large); err != nil || info.Size() != size {
+		w, err := f.Create(large)
+		if err != nil {
+			return err
+		}
+		if _, err := io.CopyN(w, rand.Reader, size); err != nil {
+			w.Abort()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+
+	prefix := fmt.Sprintf("list-%d", keys)
+	if dir, err := f.Open(prefix); err == nil {
+		entries, _ := dir.Readdir(-1)
+		dir.Close()
+		if int64(len(entries)) == keys {
+			return nil
+		}
+	}
+
+	for i := int64(0); i < keys; i++ {
+		_, err := f.putObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(f.bucket),
+			Key:    aws.String(fmt.Sprintf("%s/%08d", prefix, i)),
+			Body:   bytes.NewReader(nil),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func BenchmarkSequentialRead(b *testing.B) {
+	f := benchFileSystem(b)
+	size := benchEnv(b, "S3FS_BENCH_SIZE", 64<<20)
+	name := fmt.Sprintf("large-%d.bin", size)
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file, err := f.Open(name)
+		if err != nil {
+			b.Fatalf("error: %v", err)
+		}
+		if _, err := io.CopyN(io.Discard, file, size); err != nil {
+			b.Fatalf("error: %v", err)
+		}
+		file.Close()
+	}
+}
+
+func BenchmarkRangedRead(b *testing.B) {
+	f := benchFileSystem(b)
+	size := benchEnv(b, "S3FS_BENCH_SIZE", 64<<20)
+	rangeSize := benchEnv(b, "S3FS_BENCH_RANGE", 64<<10)
+
+	file, err := f.Open(fmt.Sprintf("large-%d.bin", size))
+	if err != nil {
+		b.Fatalf("error: %v", err)
+	}
+	defer file.Close()
+
+	random := mrand.New(mrand.NewSource(1))
+	buf := make([]byte, rangeSize)
+
+	b.SetBytes(rangeSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := file.Seek(random.Int63n(size-rangeSize), io.SeekStart); err != nil {
+			b.Fatalf("error: %v", err)
+		}
+		if _, err := io.ReadFull(file, buf); err != nil {
+			b.Fatalf("error: %v", err)
+		}
+	}
+}
+
+func BenchmarkParallelDownload(b *testing.B) {
+	f := benchFileSystem(b)
+	size := benchEnv(b, "S3FS_BENCH_SIZE", 64<<20)
+	opts := DownloadOptions{Concurrency: int(benchEnv(b, "S3FS_BENCH_CONCURRENCY", 8))}
+	localPath := filepath.Join(b.TempDir(), "large.bin")
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := f.DownloadTo(context.Background(), fmt.Sprintf("large-%d.bin", size), localPath, opts); err != nil {
+			b.Fatalf("error: %v", err)
+		}
+	}
+}
+
+func BenchmarkListLargePrefix(b *testing.B) {
+	f := benchFileSystem(b)
+	keys := benchEnv(b, "S3FS_BENCH_KEYS", 5000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dir, err := f.Open(fmt.Sprintf("list-%d", keys))
+		if err != nil {
+			b.Fatalf("error: %v", err)
+		}
+		entries, err := dir.Readdir(-1)
+		dir.Close()
+		if err != nil {
+			b.Fatalf("error: %v", err)
+		}
+		if int64(len(entries)) != keys {
+			b.Fatalf("error: listed %d keys, want %d", len(entries), keys)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// objectBody wraps the body of a GetObject. The span of the request stays open until the body
+// is closed, and closing records the number of bytes read on the span and in the log.
+type objectBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	span   trace.Span
+	logger Logger
+	key    string
+	rng    string
+	start  time.Time
+	n      int64
+	once   sync.Once
+}
+
+func (b *objectBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *objectBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.span.SetAttributes(attribute.Int64("s3fs.bytes", b.n))
+		b.span.End()
+		b.logger.Log(b.ctx, slog.LevelDebug, "s3fs: body closed",
+			"key", b.key, "range", b.rng, "bytes", b.n, "duration", time.Since(b.start))
+	})
+
+	return err
+}
@@ -0,0 +1,88 @@
+package s3fs
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrChecksumMismatch is returned by File.Close when the checksum of the bytes read doesn't
+// match the checksum stored by S3. See WithChecksumVerification
+var ErrChecksumMismatch = errors.New("s3fs: checksum mismatch")
+
+// WithChecksumVerification makes Open request the checksums of objects and File compute the
+// checksum of the bytes read. Close returns ErrChecksumMismatch if the object was read to the
+// end and the checksums differ. The SHA256, SHA1, CRC32C or CRC32 checksum stored by S3 is
+// used, in that order, or else the ETag if it is the MD5 of the object, which isn't the case
+// for multipart uploads and objects encrypted with SSE-KMS or SSE-C. Objects without a usable
+// checksum, ranged opens and Files that weren't read sequentially from the start aren't verified.
+func WithChecksumVerification() Option {
+	return func(f *FileSystem) {
+		f.verifyChecksums = true
+	}
+}
+
+// checksum compares the hash of the bytes written to it with the checksum of an object
+type checksum struct {
+	hash hash.Hash
+	want string
+	hex  bool
+	n    int64
+}
+
+// newChecksum returns a checksum for the object or nil if it has no usable checksum
+func newChecksum(object *s3.GetObjectOutput) *checksum {
+	switch {
+	case isFullChecksum(object.ChecksumSHA256):
+		return &checksum{hash: sha256.New(), want: *object.ChecksumSHA256}
+	case isFullChecksum(object.ChecksumSHA1):
+		return &checksum{hash: sha1.New(), want: *object.ChecksumSHA1}
+	case isFullChecksum(object.ChecksumCRC32C):
+		return &checksum{hash: crc32.New(crc32.MakeTable(crc32.Castagnoli)), want: *object.ChecksumCRC32C}
+	case isFullChecksum(object.ChecksumCRC32):
+		return &checksum{hash: crc32.NewIEEE(), want: *object.ChecksumCRC32}
+	}
+
+	etag := strings.Trim(aws.StringValue(object.ETag), `"`)
+	sse := aws.StringValue(object.ServerSideEncryption)
+	if len(etag) != md5.Size*2 || sse == s3.ServerSideEncryptionAwsKms || sse == "aws:kms:dsse" || object.SSECustomerAlgorithm != nil {
+		return nil
+	}
+
+	return &checksum{hash: md5.New(), want: etag, hex: true}
+}
+
+// isFullChecksum reports whether a checksum covers the whole object. The checksums of
+// multipart uploads are checksums of the part checksums and end with the number of parts.
+func isFullChecksum(c *string) bool {
+	return c != nil && *c != "" && !strings.Contains(*c, "-")
+}
+
+func (c *checksum) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return c.hash.Write(p)
+}
+
+func (c *checksum) verify() error {
+	sum := c.hash.Sum(nil)
+
+	got := base64.StdEncoding.EncodeToString(sum)
+	if c.hex {
+		got = hex.EncodeToString(sum)
+	}
+
+	if got != c.want {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package s3fs
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestChecksum(t *testing.T) {
+	content := []byte("PassengerId,Survived,Pclass\n1,0,3\n")
+	sha := sha256.Sum256(content)
+	sum := md5.Sum(content)
+
+	cases := []struct {
+		description string
+		object      *s3.GetObjectOutput
+		verified    bool
+	}{
+		{"sha256", &s3.GetObjectOutput{ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(sha[:]))}, true},
+		{"etag", &s3.GetObjectOutput{ETag: aws.String(`"` + hex.EncodeToString(sum[:]) + `"`)}, true},
+		{"multipart etag", &s3.GetObjectOutput{ETag: aws.String(`"` + hex.EncodeToString(sum[:]) + `-3"`)}, false},
+		{"kms etag", &s3.GetObjectOutput{
+			ETag:                 aws.String(`"` + hex.EncodeToString(sum[:]) + `"`),
+			ServerSideEncryption: aws.String(s3.ServerSideEncryptionAwsKms),
+		}, false},
+		{"composite sha256", &s3.GetObjectOutput{ChecksumSHA256: aws.String("abc-2")}, false},
+	}
+
+	for _, c := range cases {
+		check := newChecksum(c.object)
+		if (check != nil) != c.verified {
+			t.Fatalf("error: %s: expected verification %v", c.description, c.verified)
+		}
+		if check == nil {
+			continue
+		}
+
+		check.Write(content)
+		if err := check.verify(); err != nil {
+			t.Fatalf("error: %s: %s", c.description, err)
+		}
+
+		check = newChecksum(c.object)
+		check.Write(content[1:])
+		if err := check.verify(); err != ErrChecksumMismatch {
+			t.Fatalf("error: %s: expected ErrChecksumMismatch, got %v", c.description, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build linux || darwin || freebsd
+
+// Command s3fs-mount mounts an S3 bucket as a FUSE file system.
+//
+//	s3fs-mount [-region us-east-1] [-ro] bucket mountpoint
+//
+// Credentials are found like the AWS CLI finds them. The file system is unmounted on
+// SIGINT or SIGTERM.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/shijuleon/s3fs"
+	"github.com/shijuleon/s3fs/mount"
+)
+
+func main() {
+	region := flag.String("region", os.Getenv("AWS_REGION"), "region of the bucket")
+	readOnly := flag.Bool("ro", false, "mount read-only")
+	folderMarkers := flag.Bool("folder-markers", true, "create folder marker objects for new directories")
+	debug := flag.Bool("debug", false, "log every FUSE request")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] bucket mountpoint\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	bucket, dir := flag.Arg(0), flag.Arg(1)
+
+	opts := []s3fs.Option{s3fs.WithKeyMapper(s3fs.PathKeyMapper)}
+	if *folderMarkers {
+		opts = append(opts, s3fs.WithFolderMarkers())
+	}
+	fsys := s3fs.New(bucket, *region, opts...)
+
+	server, err := mount.Mount(dir, fsys, mount.Options{ReadOnly: *readOnly, Debug: *debug})
+	if err != nil {
+		log.Fatalf("s3fs-mount: %v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		if err := server.Unmount(); err != nil {
+			log.Printf("s3fs-mount: unmount: %v", err)
+		}
+	}()
+
+	server.Wait()
+}
@@ -0,0 +1,351 @@
+// Command s3fscli uses the s3fs package from the shell. Objects are named with s3:// URLs.
+//
+//	s3fscli ls s3://bucket/docs/
+//	s3fscli cat [-range 0-1023] s3://bucket/docs/setup.html
+//	s3fscli stat s3://bucket/docs/setup.html
+//	s3fscli cp [-p 8] s3://bucket/backup.tar backup.tar
+//	s3fscli cp backup.tar s3://bucket/backup.tar
+//	s3fscli serve [-addr :8080] [-index index.html] s3://bucket
+//
+// The region is taken from -region or AWS_REGION and credentials are found like the AWS CLI
+// finds them. Downloads use parallel ranged requests; uploads are streamed as a multipart upload.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shijuleon/s3fs"
+)
+
+const usage = `usage: s3fscli [-region region] command [flags] args
+
+commands:
+  ls s3://bucket/prefix/       list the objects and directories under a prefix
+  cat s3://bucket/key          write an object to the standard output
+  stat s3://bucket/key         describe an object or a directory
+  cp src dst                   copy between a local file and an object
+  serve s3://bucket            serve the bucket over HTTP
+`
+
+func main() {
+	region := flag.String("region", os.Getenv("AWS_REGION"), "region of the bucket")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	commands := map[string]func(ctx context.Context, region string, args []string) error{
+		"ls":    ls,
+		"cat":   cat,
+		"stat":  stat,
+		"cp":    cp,
+		"serve": serve,
+	}
+
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := command(ctx, *region, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "s3fscli %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+// open returns the FileSystem of the bucket of an s3:// URL and the name of the object
+func open(region, rawURL string) (*s3fs.FileSystem, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, "", fmt.Errorf("%s isn't an s3://bucket/key URL", rawURL)
+	}
+
+	name := u.Path
+	if name == "" {
+		name = "/"
+	}
+
+	return s3fs.New(u.Host, region, s3fs.WithKeyMapper(s3fs.PathKeyMapper)), name, nil
+}
+
+func isS3URL(s string) bool {
+	return strings.HasPrefix(s, "s3://")
+}
+
+// bucket returns the bucket of an s3:// URL
+func bucket(s3URL string) string {
+	b, _, _ := strings.Cut(strings.TrimPrefix(s3URL, "s3://"), "/")
+	return b
+}
+
+// parseFlags parses the flags of a command and checks its number of arguments
+func parseFlags(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != n {
+		return fmt.Errorf("expected %d arguments, got %d", n, fs.NArg())
+	}
+	return nil
+}
+
+func ls(ctx context.Context, region string, args []string) error {
+	flags := flag.NewFlagSet("ls", flag.ExitOnError)
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	fsys, name, err := open(region, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	dir, err := fsys.OpenContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for {
+		entries, err := dir.Readdir(1000)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				fmt.Fprintf(w, "%s\t\t%s/\t\n", "DIR", entry.Name())
+				continue
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t\n", entry.Size(), entry.ModTime().Format(time.DateTime), entry.Name())
+		}
+		if errors.Is(err, io.EOF) || (err == nil && len(entries) == 0) {
+			return w.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func cat(ctx context.Context, region string, args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ExitOnError)
+	byteRange := flags.String("range", "", "inclusive byte range start-end to write, like 0-1023 or 1024-")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	fsys, name, err := open(region, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	file, err := fsys.OpenContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	start, end := int64(0), info.Size()-1
+	if *byteRange != "" {
+		if start, end, err = parseRange(*byteRange, info.Size()); err != nil {
+			return err
+		}
+		if _, err := file.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.CopyN(os.Stdout, file, end-start+1)
+	return err
+}
+
+// parseRange parses an inclusive start-end range; a missing end is the end of the object
+func parseRange(s string, size int64) (int64, int64, error) {
+	startText, endText, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+
+	end := size - 1
+	if endText != "" {
+		if end, err = strconv.ParseInt(endText, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+	}
+
+	if start < 0 || end < start || start >= size {
+		return 0, 0, fmt.Errorf("range %q not satisfiable for %d bytes", s, size)
+	}
+	return start, min(end, size-1), nil
+}
+
+func stat(ctx context.Context, region string, args []string) error {
+	flags := flag.NewFlagSet("stat", flag.ExitOnError)
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	fsys, name, err := open(region, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	info, err := fsys.StatContext(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("name: %s\n", info.Name())
+	if info.IsDir() {
+		fmt.Println("type: directory")
+		return nil
+	}
+	fmt.Println("type: object")
+	fmt.Printf("size: %d\n", info.Size())
+	fmt.Printf("modified: %s\n", info.ModTime().Format(time.RFC3339))
+
+	file, err := fsys.OpenContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if f, ok := file.(*s3fs.File); ok {
+		fmt.Printf("content-type: %s\n", f.ContentType())
+		fmt.Printf("etag: %s\n", f.ETag())
+	}
+	return nil
+}
+
+func cp(ctx context.Context, region string, args []string) error {
+	flags := flag.NewFlagSet("cp", flag.ExitOnError)
+	parallel := flags.Int("p", 4, "number of ranges downloaded in parallel")
+	chunkSize := flags.Int64("chunk", 8<<20, "size of the ranges downloaded in parallel")
+	if err := parseFlags(flags, args, 2); err != nil {
+		return err
+	}
+	src, dst := flags.Arg(0), flags.Arg(1)
+
+	switch {
+	case isS3URL(src) && isS3URL(dst):
+		srcFS, srcName, err := open(region, src)
+		if err != nil {
+			return err
+		}
+		dstFS, dstName, err := open(region, dst)
+		if err != nil {
+			return err
+		}
+		if bucket(src) == bucket(dst) {
+			return srcFS.Copy(srcName, dstName)
+		}
+
+		file, err := srcFS.OpenContext(ctx, srcName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return upload(dstFS, file, dstName)
+	case isS3URL(src):
+		fsys, name, err := open(region, src)
+		if err != nil {
+			return err
+		}
+		return fsys.DownloadTo(ctx, name, dst, s3fs.DownloadOptions{
+			StateFile:   dst + ".s3fs-download",
+			ChunkSize:   *chunkSize,
+			Concurrency: *parallel,
+		})
+	case isS3URL(dst):
+		fsys, name, err := open(region, dst)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return upload(fsys, file, name)
+	}
+
+	return errors.New("one of src and dst must be an s3:// URL")
+}
+
+// upload streams r to the object with a multipart upload
+func upload(fsys *s3fs.FileSystem, r io.Reader, name string) error {
+	w, err := fsys.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+func serve(ctx context.Context, region string, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	index := flags.String("index", "index.html", "object served for paths ending in a slash")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "s3" || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+		return fmt.Errorf("%s isn't an s3://bucket URL", flags.Arg(0))
+	}
+
+	fsys := s3fs.New(u.Host, region, s3fs.WithKeyMapper(s3fs.PathKeyMapper), s3fs.WithIndexFile(*index))
+	server := &http.Server{Addr: *addr, Handler: s3fs.FileServer(fsys)}
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(os.Stderr, "serving s3://%s on %s\n", u.Host, *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
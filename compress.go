@@ -0,0 +1,276 @@
+package s3fs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultCompressibleTypes are the media types compressed by WithCompression by default
+var DefaultCompressibleTypes = []string{
+	"text/*",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// CompressionOptions configures the compression of FileServer. See WithCompression
+type CompressionOptions struct {
+	// ContentTypes are media types, or prefixes ending in "*", of the objects to compress.
+	// It defaults to DefaultCompressibleTypes.
+	ContentTypes []string
+	// MinSize is the size under which objects are served uncompressed, 1024 by default
+	MinSize int64
+	// MaxSize is the size over which objects are served uncompressed, 10 MiB by default,
+	// since they are compressed in memory
+	MaxSize int64
+	// CacheSize is the total size of the compressed variants kept in memory, 32 MiB by default
+	CacheSize int64
+}
+
+// WithCompression makes FileServer compress objects with a text-like content type with
+// brotli or gzip when the client accepts it. Compressed variants are cached by key, ETag and
+// encoding, in a least recently used cache of CacheSize bytes shared by every request of the
+// handler. Objects stored with a Content-Encoding are served as they are.
+func WithCompression(opts CompressionOptions) HandlerOption {
+	if opts.ContentTypes == nil {
+		opts.ContentTypes = DefaultCompressibleTypes
+	}
+	if opts.MinSize == 0 {
+		opts.MinSize = 1024
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = 10 << 20
+	}
+	if opts.CacheSize == 0 {
+		opts.CacheSize = 32 << 20
+	}
+
+	return func(h *fileHandler) {
+		h.compressor = &compressor{opts: opts, cache: newVariantCache(opts.CacheSize)}
+	}
+}
+
+type compressor struct {
+	opts  CompressionOptions
+	cache *variantCache
+}
+
+// serve writes the compressed variant of file and reports whether it handled the request.
+// It leaves the request to http.ServeContent when the file shouldn't be compressed.
+func (c *compressor) serve(w http.ResponseWriter, r *http.Request, file *File) bool {
+	if file.header.Get("Content-Encoding") != "" || file.etag == "" ||
+		file.stat.size < c.opts.MinSize || file.stat.size > c.opts.MaxSize {
+		return false
+	}
+
+	contentType := file.contentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(file.key))
+	}
+	if !c.compressible(contentType) {
+		return false
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return false
+	}
+
+	cacheKey := file.key + "\x00" + file.etag + "\x00" + encoding
+	data, ok := c.cache.get(cacheKey)
+	file.fs.cacheLookup(r.Context(), file.key, encoding, ok)
+	if !ok {
+		var err error
+		data, err = compress(file, encoding)
+		if err != nil {
+			msg, code := toHTTPError(err)
+			http.Error(w, msg, code)
+			return true
+		}
+		c.cache.add(cacheKey, data)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Encoding", encoding)
+	// the compressed variant is a different representation, so it needs its own ETag
+	w.Header().Set("Etag", strings.TrimSuffix(file.etag, `"`)+"-"+encoding+`"`)
+	http.ServeContent(w, r, file.stat.name, file.stat.modTime, bytes.NewReader(data))
+	return true
+}
+
+// compressible reports whether contentType matches one of the ContentTypes
+func (c *compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range c.opts.ContentTypes {
+		if prefix, ok := strings.CutSuffix(t, "*"); ok {
+			if strings.HasPrefix(mediaType, prefix) {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// compress reads the whole file and compresses it with encoding
+func compress(file *File, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var w io.WriteCloser
+	if encoding == "br" {
+		w = brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+	} else {
+		w = gzip.NewWriter(&buf)
+	}
+
+	if _, err := io.CopyN(w, file, file.stat.size); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// acceptedEncoding returns "br" or "gzip", whichever is accepted with the highest quality by
+// the Accept-Encoding header, preferring brotli, or "" if neither is. "*" only stands for the
+// codings the header doesn't name.
+func acceptedEncoding(header string) string {
+	named := map[string]float64{}
+	wildcard := 0.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch coding {
+		case "br", "gzip":
+			named[coding] = q
+		case "*":
+			wildcard = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"br", "gzip"} {
+		q, ok := named[coding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+
+	return best
+}
+
+// variantCache is a least recently used cache of compressed variants limited by their size
+type variantCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type variant struct {
+	key  string
+	data []byte
+}
+
+func newVariantCache(maxSize int64) *variantCache {
+	return &variantCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (c *variantCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	return e.Value.(*variant).data, true
+}
+
+func (c *variantCache) add(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if int64(len(data)) > c.maxSize {
+		return
+	}
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&variant{key: key, data: data})
+	c.size += int64(len(data))
+
+	for c.size > c.maxSize {
+		oldest := c.order.Back()
+		v := c.order.Remove(oldest).(*variant)
+		delete(c.entries, v.key)
+		c.size -= int64(len(v.data))
+	}
+}
+
+// evict drops the variants of the object with the key
+func (c *variantCache) evict(key string) {
+	prefix := key + "\x00"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, e := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			c.order.Remove(e)
+			delete(c.entries, k)
+			c.size -= int64(len(e.Value.(*variant).data))
+		}
+	}
+}
+
+// disable drops every variant and stops caching new ones
+func (c *variantCache) disable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize, c.size = 0, 0
+	c.order.Init()
+	c.entries = map[string]*list.Element{}
+}
@@ -0,0 +1,116 @@
+package s3fs
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip":                      "gzip",
+		"gzip, deflate, br":         "br",
+		"br;q=0.5, gzip;q=0.8":      "gzip",
+		"br;q=0, gzip":              "gzip",
+		"*":                         "br",
+		"br;q=0, *":                 "gzip",
+		"*;q=0.5, gzip":             "gzip",
+		"br;q=0, gzip;q=0, *":       "",
+		"GZIP;q=1.0, br;q=0":        "gzip",
+		"deflate, gzip;q=0, br;q=0": "",
+	}
+
+	for header, want := range tests {
+		if got := acceptedEncoding(header); got != want {
+			t.Fatalf("error: expected %q for %q, got %q", want, header, got)
+		}
+	}
+}
+
+func TestVariantCache(t *testing.T) {
+	c := newVariantCache(10)
+	c.add("a", make([]byte, 4))
+	c.add("b", make([]byte, 4))
+	c.get("a")
+	c.add("c", make([]byte, 4))
+
+	if _, ok := c.get("b"); ok {
+		t.Fatalf("error: expected least recently used b to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("error: expected a to be cached")
+	}
+	c.add("d", make([]byte, 11))
+	if _, ok := c.get("d"); ok {
+		t.Fatalf("error: expected variant larger than the cache not to be cached")
+	}
+}
+
+func TestCompressorServe(t *testing.T) {
+	content := strings.Repeat("<p>hello</p>", 200)
+	m := &recordingMetrics{}
+	file := &File{
+		fs:          *New("public-sample-data", "us-east-1", WithMetrics(m)),
+		key:         "index.html",
+		body:        io.NopCloser(strings.NewReader(content)),
+		stat:        fileStat{name: "index.html", size: int64(len(content))},
+		contentType: "text/html; charset=utf-8",
+		etag:        `"abc"`,
+	}
+
+	var h fileHandler
+	WithCompression(CompressionOptions{})(&h)
+
+	r := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	if !h.compressor.serve(w, r, file) {
+		t.Fatalf("error: expected the file to be compressed")
+	}
+
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Etag") != `"abc-gzip"` {
+		t.Fatalf("error: unexpected headers %v", w.Header())
+	}
+
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if string(body) != content {
+		t.Fatalf("error: decompressed body doesn't match the object")
+	}
+
+	// the second request is served from the cache without reading the object
+	if !h.compressor.serve(httptest.NewRecorder(), r, file) {
+		t.Fatalf("error: expected the cached variant to be served")
+	}
+	if m.hits != 1 || m.misses != 1 {
+		t.Fatalf("error: expected a cache miss and a hit, got %d misses and %d hits", m.misses, m.hits)
+	}
+
+	file.contentType = "image/png"
+	if h.compressor.serve(httptest.NewRecorder(), r, file) {
+		t.Fatalf("error: expected image/png not to be compressed")
+	}
+}
+
+func TestVariantCacheEvict(t *testing.T) {
+	c := newVariantCache(100)
+	c.add("index.html\x00\"1\"\x00br", make([]byte, 4))
+	c.add("index.html\x00\"1\"\x00gzip", make([]byte, 4))
+	c.add("index.html.bak\x00\"2\"\x00gzip", make([]byte, 4))
+
+	c.evict("index.html")
+	if len(c.entries) != 1 || c.size != 4 {
+		t.Fatalf("error: expected only the variant of index.html.bak to be kept, got %d variants", len(c.entries))
+	}
+}
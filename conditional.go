@@ -0,0 +1,63 @@
+package s3fs
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// OpenIfChanged opens the object with the name unless its ETag is still knownETag, as
+// returned by File.ETag of an earlier Open. If the object didn't change, OpenIfChanged returns
+// a nil File and false without transferring the object: S3 answers the GetObject conditional
+// on If-None-Match with 304 Not Modified. An empty knownETag always opens the object.
+func (f FileSystem) OpenIfChanged(ctx context.Context, name, knownETag string) (http.File, bool, error) {
+	if knownETag == "" {
+		fi, err := f.OpenContext(ctx, name)
+		return fi, err == nil, err
+	}
+
+	key, err := f.key(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	object, err := f.getObject(ctx, &s3.GetObjectInput{
+		Bucket:      aws.String(f.bucket),
+		Key:         aws.String(key),
+		IfNoneMatch: aws.String(knownETag),
+	})
+	if isNotModified(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var fi *File
+	if ref, ok := contentRef(object.Metadata, aws.Int64Value(object.ContentLength)); ok {
+		// the ETag of a pointer object never changes, so the content is compared instead
+		fi, err = f.openRef(ctx, key, ref, object)
+		if err == nil && fi.etag == knownETag {
+			fi.Close()
+			return nil, false, nil
+		}
+	} else {
+		fi, err = newFile(ctx, f, key, object)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return fi, true, nil
+}
+
+// isNotModified reports whether err is the 304 response to a GetObject with IfNoneMatch
+func isNotModified(err error) bool {
+	if aerr, ok := err.(awserr.RequestFailure); ok {
+		return aerr.StatusCode() == http.StatusNotModified
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package s3fs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOpenIfChanged(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv)
+	ctx := context.Background()
+
+	f, changed, err := s3Fs.OpenIfChanged(ctx, "passengers.txt", "")
+	if err != nil || !changed {
+		t.Fatalf("error: expected the object to be opened, got %v, %v", changed, err)
+	}
+	etag := f.(*File).ETag()
+	f.Close()
+
+	f, changed, err = s3Fs.OpenIfChanged(ctx, "passengers.txt", etag)
+	if err != nil || changed || f != nil {
+		t.Fatalf("error: expected the object to be unchanged, got %v, %v", changed, err)
+	}
+
+	srv.PutObject("public-sample-data", "passengers.txt", []byte("new passengers"), "text/plain")
+	f, changed, err = s3Fs.OpenIfChanged(ctx, "passengers.txt", etag)
+	if err != nil || !changed {
+		t.Fatalf("error: expected the object to be changed, got %v, %v", changed, err)
+	}
+	defer f.Close()
+
+	data, err := ReadFile(s3Fs, "passengers.txt")
+	if err != nil || string(data) != "new passengers" {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+}
+
+func TestOpenIfChangedDeduplicated(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t), WithDeduplication("blobs/"))
+	ctx := context.Background()
+
+	if err := s3Fs.WriteFile("app.js", []byte("v1"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	f, _ := s3Fs.Open("app.js")
+	etag := f.(*File).ETag()
+	f.Close()
+
+	if _, changed, err := s3Fs.OpenIfChanged(ctx, "app.js", etag); err != nil || changed {
+		t.Fatalf("error: expected the object to be unchanged, got %v, %v", changed, err)
+	}
+
+	if err := s3Fs.WriteFile("app.js", []byte("v2"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	f, changed, err := s3Fs.OpenIfChanged(ctx, "app.js", etag)
+	if err != nil || !changed {
+		t.Fatalf("error: expected the object to be changed, got %v, %v", changed, err)
+	}
+	f.Close()
+}
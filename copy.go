@@ -0,0 +1,255 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxCopyObjectSize is the largest object CopyObject can copy in a single request. It is a
+// variable so that tests can copy small objects in parts.
+var maxCopyObjectSize int64 = 5 << 30
+
+const (
+	// copyPartSize is the size of the parts of a multipart copy
+	copyPartSize = 512 << 20
+	// maxParts is the maximum number of parts of a multipart upload
+	maxParts = 10000
+)
+
+// Copy copies the object src to dst within the bucket. The copy is done by S3 with CopyObject,
+// or with a multipart copy for objects larger than 5GB, so no data passes through the caller.
+// Metadata of src is kept. Copying an object onto its own key does nothing.
+func (f FileSystem) Copy(src, dst string) error {
+	srcKey, err := f.key(src)
+	if err != nil {
+		return err
+	}
+
+	dstKey, err := f.key(dst)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if srcKey == dstKey {
+		return f.statKey(ctx, srcKey)
+	}
+	return f.copyObject(ctx, srcKey, dstKey)
+}
+
+// Move renames src to dst by copying it with Copy and deleting src. Moving an object onto its
+// own key does nothing, rather than deleting it after the copy.
+func (f FileSystem) Move(src, dst string) error {
+	srcKey, err := f.key(src)
+	if err != nil {
+		return err
+	}
+
+	dstKey, err := f.key(dst)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if srcKey == dstKey {
+		return f.statKey(ctx, srcKey)
+	}
+	if err := f.copyObject(ctx, srcKey, dstKey); err != nil {
+		return err
+	}
+
+	return f.deleteObject(ctx, srcKey)
+}
+
+// statKey returns os.ErrNotExist if there is no object with the key
+func (f FileSystem) statKey(ctx context.Context, key string) error {
+	_, err := f.headObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (f FileSystem) copyObject(ctx context.Context, srcKey, dstKey string) error {
+	head, err := f.headObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(srcKey),
+	})
+	if err != nil {
+		return err
+	}
+
+	if aws.Int64Value(head.ContentLength) > maxCopyObjectSize {
+		return f.multipartCopy(ctx, srcKey, dstKey, head)
+	}
+
+	start := time.Now()
+	_, err = f.s3.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(f.bucket),
+		Key:               aws.String(dstKey),
+		CopySource:        aws.String(copySource(f.bucket, srcKey)),
+		CopySourceIfMatch: head.ETag,
+	})
+	f.requestDone(ctx, "CopyObject", start, err, "key", dstKey, "source", srcKey)
+	f.staging.drop(dstKey)
+
+	return toFSError(err)
+}
+
+// multipartCopy copies an object larger than maxCopyObjectSize with UploadPartCopy
+func (f FileSystem) multipartCopy(ctx context.Context, srcKey, dstKey string, head *s3.HeadObjectOutput) error {
+	uploadID, err := f.createMultipartUpload(ctx, dstKey, head)
+	if err != nil {
+		return err
+	}
+
+	parts, err := f.copyParts(ctx, srcKey, dstKey, uploadID, head)
+	if err != nil {
+		f.abortMultipartUpload(ctx, dstKey, uploadID)
+		return err
+	}
+
+	return f.completeMultipartUpload(ctx, dstKey, uploadID, parts)
+}
+
+// createMultipartUpload starts a multipart upload to key. Unlike CopyObject, a multipart upload
+// doesn't copy the metadata of a source object, so it is taken from head if it isn't nil.
+func (f FileSystem) createMultipartUpload(ctx context.Context, key string, head *s3.HeadObjectOutput) (*string, error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	}
+	if head != nil {
+		input.CacheControl = head.CacheControl
+		input.ContentDisposition = head.ContentDisposition
+		input.ContentEncoding = head.ContentEncoding
+		input.ContentLanguage = head.ContentLanguage
+		input.ContentType = head.ContentType
+		input.Metadata = head.Metadata
+		input.StorageClass = head.StorageClass
+		if expires, err := http.ParseTime(aws.StringValue(head.Expires)); err == nil {
+			input.Expires = aws.Time(expires)
+		}
+	}
+
+	start := time.Now()
+	upload, err := f.s3.CreateMultipartUploadWithContext(ctx, input)
+	f.requestDone(ctx, "CreateMultipartUpload", start, err, "key", key)
+	if err != nil {
+		return nil, toFSError(err)
+	}
+
+	return upload.UploadId, nil
+}
+
+// copyParts copies the object srcKey described by head as the first parts of the upload to dstKey
+func (f FileSystem) copyParts(ctx context.Context, srcKey, dstKey string, uploadID *string, head *s3.HeadObjectOutput) ([]*s3.CompletedPart, error) {
+	var parts []*s3.CompletedPart
+	for i, r := range copyRanges(aws.Int64Value(head.ContentLength)) {
+		number, offset, end := int64(i+1), r[0], r[1]
+
+		start := time.Now()
+		part, err := f.s3.UploadPartCopyWithContext(ctx, &s3.UploadPartCopyInput{
+			Bucket:            aws.String(f.bucket),
+			Key:               aws.String(dstKey),
+			UploadId:          uploadID,
+			PartNumber:        aws.Int64(number),
+			CopySource:        aws.String(copySource(f.bucket, srcKey)),
+			CopySourceIfMatch: head.ETag,
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+		})
+		f.requestDone(ctx, "UploadPartCopy", start, err, "key", dstKey, "part", number)
+		if err != nil {
+			return nil, toFSError(err)
+		}
+
+		parts = append(parts, &s3.CompletedPart{
+			ETag:       part.CopyPartResult.ETag,
+			PartNumber: aws.Int64(number),
+		})
+	}
+
+	return parts, nil
+}
+
+// copyRanges splits an object of size bytes into the inclusive byte ranges of the parts of a
+// multipart copy. A remainder under minPartSize is added to the last full part, since AppendWriter
+// uploads parts after the copied ones and only the final part may be smaller.
+func copyRanges(size int64) [][2]int64 {
+	partSize := int64(copyPartSize)
+	if size > partSize*maxParts {
+		partSize = (size + maxParts - 1) / maxParts
+	}
+
+	var ranges [][2]int64
+	for offset := int64(0); offset < size; {
+		end := offset + partSize - 1
+		if end >= size-1 || size-end-1 < minPartSize {
+			end = size - 1
+		}
+		ranges = append(ranges, [2]int64{offset, end})
+		offset = end + 1
+	}
+	return ranges
+}
+
+func (f FileSystem) completeMultipartUpload(ctx context.Context, key string, uploadID *string, parts []*s3.CompletedPart) error {
+	start := time.Now()
+	_, err := f.s3.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(f.bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	f.requestDone(ctx, "CompleteMultipartUpload", start, err, "key", key)
+	f.staging.drop(key)
+	if err != nil {
+		f.abortMultipartUpload(ctx, key, uploadID)
+	}
+
+	return toFSError(err)
+}
+
+// abortMultipartUpload aborts a failed multipart upload so its parts don't keep being billed.
+// The error is only logged, the caller returns the error that caused the abort.
+func (f FileSystem) abortMultipartUpload(ctx context.Context, key string, uploadID *string) {
+	start := time.Now()
+	_, err := f.s3.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(f.bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+	f.requestDone(ctx, "AbortMultipartUpload", start, err, "key", key)
+}
+
+func (f FileSystem) deleteObject(ctx context.Context, key string) error {
+	start := time.Now()
+	_, err := f.s3.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	f.requestDone(ctx, "DeleteObject", start, err, "key", key)
+	f.staging.drop(key)
+
+	return toFSError(err)
+}
+
+// copySource returns the URL-encoded CopySource for key in bucket
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	if _, ok := parseBucketARN(bucket); ok {
+		return bucket + "/object/" + strings.Join(segments, "/")
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,84 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestCopySource(t *testing.T) {
+	cases := map[string]string{
+		"passengers.txt":          "public-sample-data/passengers.txt",
+		"assets/2024/logo v2.png": "public-sample-data/assets/2024/logo%20v2.png",
+		"a/b?c#d":                 "public-sample-data/a/b%3Fc%23d",
+	}
+
+	for key, want := range cases {
+		if got := copySource("public-sample-data", key); got != want {
+			t.Fatalf("error: copy source of %s is %s, want %s", key, got, want)
+		}
+	}
+
+	accessPoint := "arn:aws:s3:us-west-2:123456789012:accesspoint/assets"
+	if got := copySource(accessPoint, "logo v2.png"); got != accessPoint+"/object/logo%20v2.png" {
+		t.Fatalf("error: copy source through the access point is %s", got)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv, WithKeyMapper(PathKeyMapper))
+
+	srv.PutObjectWithHeader("public-sample-data", "docs/setup.html", []byte("<h1>Setup</h1>"),
+		http.Header{"Content-Type": {"text/html"}, "X-Amz-Meta-Author": {"ops"}})
+	if err := s3Fs.Copy("/docs/setup.html", "/docs/install.html"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	f, err := s3Fs.Open("/docs/install.html")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+	if data, _ := io.ReadAll(f); string(data) != "<h1>Setup</h1>" || f.(*File).ContentType() != "text/html" {
+		t.Fatalf("error: unexpected copy %q of type %s", data, f.(*File).ContentType())
+	}
+	if _, ok := srv.Object("public-sample-data", "docs/setup.html"); !ok {
+		t.Fatalf("error: expected Copy to keep the source")
+	}
+
+	if err := s3Fs.Copy("/docs/setup.html", "/docs/setup.html"); err != nil {
+		t.Fatalf("error: copying onto itself: %v", err)
+	}
+	if err := s3Fs.Copy("/missing.html", "/copy.html"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestMove(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv)
+
+	if err := s3Fs.Move("passengers.txt", "passengers.txt"); err != nil {
+		t.Fatalf("error: moving onto itself: %v", err)
+	}
+	if data, ok := srv.Object("public-sample-data", "passengers.txt"); !ok || len(data) != 1046 {
+		t.Fatalf("error: expected moving onto itself to keep the object")
+	}
+
+	if err := s3Fs.Move("passengers.txt", "travellers.txt"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, ok := srv.Object("public-sample-data", "passengers.txt"); ok {
+		t.Fatalf("error: expected Move to delete the source")
+	}
+	if data, ok := srv.Object("public-sample-data", "travellers.txt"); !ok || len(data) != 1046 {
+		t.Fatalf("error: expected the moved object")
+	}
+
+	if err := s3Fs.Move("missing.txt", "missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+}
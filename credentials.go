@@ -0,0 +1,70 @@
+package s3fs
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// WithAnonymousCredentials makes the FileSystem send unsigned requests, so public buckets
+// can be read from environments without any AWS credentials
+func WithAnonymousCredentials() Option {
+	return func(f *FileSystem) {
+		f.config.Credentials = credentials.AnonymousCredentials
+	}
+}
+
+// AssumeRole describes an IAM role to assume. See WithAssumeRole
+type AssumeRole struct {
+	// RoleARN is the ARN of the role
+	RoleARN string
+	// ExternalID is passed to AssumeRole if the trust policy of the role requires it
+	ExternalID string
+	// SessionName identifies the session in CloudTrail. A name is generated if it is empty.
+	SessionName string
+	// Duration is the lifetime of the temporary credentials, 15 minutes if it is zero
+	Duration time.Duration
+	// ExpiryWindow is how long before they expire the credentials are refreshed, one minute
+	// if it is zero, so that requests don't fail with expired credentials
+	ExpiryWindow time.Duration
+}
+
+// WithAssumeRole makes the FileSystem use temporary credentials of role, obtained from STS
+// with the default credentials of the environment. The credentials are refreshed before
+// they expire, so long-running servers can keep reading buckets of another account.
+func WithAssumeRole(role AssumeRole) Option {
+	return func(f *FileSystem) {
+		f.assumeRole = &role
+	}
+}
+
+// credentials returns the credentials of the role. STS is called with the default
+// credentials of sess in the region and with the HTTP client of config.
+func (role AssumeRole) credentials(sess *session.Session, config *aws.Config) *credentials.Credentials {
+	sts := sess.Copy(&aws.Config{
+		Region:              config.Region,
+		HTTPClient:          config.HTTPClient,
+		STSRegionalEndpoint: endpoints.RegionalSTSEndpoint,
+	})
+
+	return stscreds.NewCredentials(sts, role.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if role.ExternalID != "" {
+			p.ExternalID = aws.String(role.ExternalID)
+		}
+		if role.SessionName != "" {
+			p.RoleSessionName = role.SessionName
+		}
+		if role.Duration != 0 {
+			p.Duration = role.Duration
+		}
+
+		p.ExpiryWindow = role.ExpiryWindow
+		if p.ExpiryWindow == 0 {
+			p.ExpiryWindow = time.Minute
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package s3fs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+func TestAnonymousCredentials(t *testing.T) {
+	s3Fs := New("public-sample-data", "us-east-1", WithAnonymousCredentials())
+	if s3Fs.s3.Config.Credentials != credentials.AnonymousCredentials {
+		t.Fatalf("error: the S3 client doesn't use anonymous credentials")
+	}
+}
+
+func TestAssumeRole(t *testing.T) {
+	s3Fs := New("public-sample-data", "us-east-1", WithAssumeRole(AssumeRole{
+		RoleARN:     "arn:aws:iam::123456789012:role/s3fs-reader",
+		ExternalID:  "s3fs",
+		SessionName: "s3fs-test",
+	}))
+
+	if s3Fs.s3.Config.Credentials == nil || s3Fs.s3.Config.Credentials == credentials.AnonymousCredentials {
+		t.Fatalf("error: the S3 client doesn't use the credentials of the role")
+	}
+}
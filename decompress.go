@@ -0,0 +1,80 @@
+package s3fs
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// SizeUnknown is the Size of a File whose content length isn't known before it is read,
+// such as an object decompressed with WithDecompression
+const SizeUnknown = -1
+
+// ErrNotSeekable is returned by Seek and ReadAt on a File of unknown size
+var ErrNotSeekable = errors.New("s3fs: file of unknown size isn't seekable")
+
+// WithDecompression makes Open decompress objects stored with Content-Encoding gzip, so they
+// read as their original content. The size of the original content isn't known, so the Size of
+// these Files is SizeUnknown and they can only be read sequentially. FileServer sends them with
+// chunked transfer encoding instead of using http.ServeContent.
+func WithDecompression() Option {
+	return func(f *FileSystem) {
+		f.decompress = true
+	}
+}
+
+// identityEncoding asks for the stored bytes of objects. Otherwise the HTTP client asks
+// for gzip on its own and transparently decompresses objects stored with Content-Encoding
+// gzip, dropping their Content-Length, unless the request has a Range.
+func identityEncoding(r *request.Request) {
+	if r.Operation.Name == "GetObject" {
+		r.HTTPRequest.Header.Set("Accept-Encoding", "identity")
+	}
+}
+
+// gzipBody decompresses the body of an object
+type gzipBody struct {
+	zr   *gzip.Reader
+	body io.ReadCloser
+}
+
+func newGzipBody(body io.ReadCloser) (*gzipBody, error) {
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return &gzipBody{zr: zr, body: body}, nil
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	b.zr.Close()
+	return b.body.Close()
+}
+
+// readStream reads a File of unknown size, filling p unless the content ends first
+func (f *File) readStream(p []byte) (int, error) {
+	p, err := f.limitStream(p)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(f.body, p)
+	f.offset += int64(n)
+	f.progress.add(int64(n))
+	if err != nil {
+		f.progress.finish()
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, nil
+	}
+	return n, err
+}
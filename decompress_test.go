@@ -0,0 +1,60 @@
+package s3fs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecompression(t *testing.T) {
+	content := strings.Repeat("s3fs ", 1000)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(content))
+	zw.Close()
+
+	srv := newTestServer(t)
+	srv.PutObjectWithHeader("public-sample-data", "app.js", buf.Bytes(), http.Header{
+		"Content-Type":     {"application/javascript"},
+		"Content-Encoding": {"gzip"},
+	})
+
+	stored, err := ReadFile(newTestFileSystem(srv), "app.js")
+	if err != nil || !bytes.Equal(stored, buf.Bytes()) {
+		t.Fatalf("error: expected the stored bytes without WithDecompression, got %d bytes, %v", len(stored), err)
+	}
+
+	s3Fs := newTestFileSystem(srv, WithDecompression())
+	f, err := s3Fs.Open("app.js")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	stat, _ := f.Stat()
+	if stat.Size() != SizeUnknown {
+		t.Fatalf("error: expected SizeUnknown, got %d", stat.Size())
+	}
+	if _, err := f.Seek(10, io.SeekStart); !errors.Is(err, ErrNotSeekable) {
+		t.Fatalf("error: expected ErrNotSeekable, got %v", err)
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != content {
+		t.Fatalf("error: expected the decompressed content, got %d bytes, %v", len(data), err)
+	}
+
+	w := httptest.NewRecorder()
+	FileServer(s3Fs).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	if w.Code != http.StatusOK || w.Body.String() != content {
+		t.Fatalf("error: expected 200 with the decompressed content, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+	if w.Header().Get("Content-Length") != "" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("error: unexpected headers %v", w.Header())
+	}
+}
@@ -0,0 +1,123 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// metaContentRef is the metadata of a pointer object holding the key of its content
+const metaContentRef = "S3fs-Content-Ref"
+
+// WithDeduplication makes WriteFile store content once under prefix, keyed by its SHA-256,
+// and write a zero-byte pointer object for the name. The upload is skipped when an object with
+// the same hash already exists, so writing an unchanged bundle of assets again only writes the
+// pointers. Open and Stat follow pointers whether or not the option is set. Content that no
+// pointer refers to anymore is not deleted.
+func WithDeduplication(prefix string) Option {
+	return func(f *FileSystem) {
+		f.dedupPrefix = prefix
+	}
+}
+
+// writeDeduplicated stores the data of input under the dedup prefix if it isn't there yet and
+// replaces the object of input with a pointer to it
+func (f FileSystem) writeDeduplicated(ctx context.Context, input *s3.PutObjectInput, plain []byte, data []byte) (*s3.PutObjectOutput, error) {
+	sum := sha256.Sum256(plain)
+	ref := f.dedupPrefix + hex.EncodeToString(sum[:])
+
+	_, err := f.headObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(ref),
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		content := *input
+		content.Key = aws.String(ref)
+		content.Body = bytes.NewReader(data)
+		_, err = f.putObject(ctx, &content)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	input.Body = bytes.NewReader(nil)
+	input.Metadata = map[string]*string{metaContentRef: aws.String(ref)}
+	return f.putObject(ctx, input)
+}
+
+// contentRef returns the key of the content of a pointer object
+func contentRef(metadata map[string]*string, size int64) (string, bool) {
+	if size != 0 {
+		return "", false
+	}
+
+	ref, ok := metadataValue(metadata, metaContentRef)
+	return ref, ok && ref != ""
+}
+
+// openRef opens the content of the pointer object with the key. The File keeps the name and
+// modification time of the pointer.
+func (f FileSystem) openRef(ctx context.Context, key, ref string, pointer *s3.GetObjectOutput) (*File, error) {
+	pointer.Body.Close()
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(ref),
+	}
+	if f.verifyChecksums {
+		input.ChecksumMode = aws.String(s3.ChecksumModeEnabled)
+	}
+
+	object, err := f.getObject(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := newFile(ctx, f, ref, object)
+	if err != nil {
+		return nil, err
+	}
+
+	fi.stat.name = path.Base(key)
+	fi.stat.modTime = aws.TimeValue(pointer.LastModified)
+	return fi, nil
+}
+
+// headContent returns the key holding the content of the object with the key and its
+// HeadObject, which are those of the content of pointer objects
+func (f FileSystem) headContent(ctx context.Context, key string) (string, *s3.HeadObjectOutput, error) {
+	head, err := f.headObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if ref, ok := contentRef(head.Metadata, aws.Int64Value(head.ContentLength)); ok {
+		head, err = f.headRef(ctx, ref, head)
+		return ref, head, err
+	}
+	return key, head, nil
+}
+
+// headRef returns the HeadObject of the content of a pointer object
+func (f FileSystem) headRef(ctx context.Context, ref string, pointer *s3.HeadObjectOutput) (*s3.HeadObjectOutput, error) {
+	object, err := f.headObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(ref),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	object.LastModified = pointer.LastModified
+	return object, nil
+}
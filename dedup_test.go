@@ -0,0 +1,134 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shijuleon/s3fs/s3test"
+)
+
+func TestDeduplication(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+	srv.CreateBucket("public-sample-data")
+	s3Fs := newTestFileSystem(srv, WithDeduplication("blobs/"), WithKeyMapper(PathKeyMapper))
+
+	bundle := "console.log('app')"
+	for _, name := range []string{"/v1/app.js", "/v2/app.js"} {
+		if err := s3Fs.WriteFile(name, []byte(bundle), ""); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+
+	var blobs int
+	for _, key := range srv.Keys("public-sample-data") {
+		data, _ := srv.Object("public-sample-data", key)
+		if strings.HasPrefix(key, "blobs/") {
+			blobs++
+			if string(data) != bundle {
+				t.Fatalf("error: unexpected content %q", data)
+			}
+		} else if len(data) != 0 {
+			t.Fatalf("error: pointer %s isn't empty", key)
+		}
+	}
+	if blobs != 1 {
+		t.Fatalf("error: expected 1 copy of the content, got %d", blobs)
+	}
+
+	data, err := ReadFile(s3Fs, "/v2/app.js")
+	if err != nil || string(data) != bundle {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+
+	f, err := s3Fs.Open("/v2/app.js")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+	stat, _ := f.Stat()
+	if stat.Name() != "app.js" || stat.Size() != int64(len(bundle)) {
+		t.Fatalf("error: unexpected stat %s %d", stat.Name(), stat.Size())
+	}
+
+	fi, err := s3Fs.Stat("/v1/app.js")
+	if err != nil || fi.Size() != int64(len(bundle)) {
+		t.Fatalf("error: unexpected Stat %v, %v", fi, err)
+	}
+}
+
+func TestDeduplicationContent(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv, WithDeduplication("blobs/"))
+
+	bundle := "console.log('app')"
+	if err := s3Fs.WriteFile("app.js", []byte(bundle), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	local := filepath.Join(t.TempDir(), "app.js")
+	if err := s3Fs.DownloadTo(context.Background(), "app.js", local, DownloadOptions{}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := os.ReadFile(local); string(data) != bundle {
+		t.Fatalf("error: DownloadTo wrote %q", data)
+	}
+
+	m, err := s3Fs.OpenRanges("app.js", [][2]int64{{0, 6}})
+	if err != nil || m.Size() != int64(len(bundle)) {
+		t.Fatalf("error: expected the size of the content, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := m.Encode(m.NewEncoder(&buf)); err != nil || !strings.Contains(buf.String(), "console") {
+		t.Fatalf("error: expected the range of the content, got %q, %v", buf.String(), err)
+	}
+
+	w, err := s3Fs.OpenAppend("app.js")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	io.WriteString(w, "\nconsole.log('more')")
+	if err := w.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	want := bundle + "\nconsole.log('more')"
+	if data, _ := srv.Object("public-sample-data", "app.js"); string(data) != want {
+		t.Fatalf("error: expected the pointer to be replaced by the appended content, got %q", data)
+	}
+	if data, err := ReadFile(s3Fs, "app.js"); err != nil || string(data) != want {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+}
+
+func TestDeduplicationSelect(t *testing.T) {
+	srv := newTestServer(t)
+
+	var selected string
+	recording := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("select") {
+			selected = r.URL.Path
+			http.Error(w, "", http.StatusNotImplemented)
+			return
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer recording.Close()
+
+	s3Fs := New("public-sample-data", "us-east-1", WithEndpoint(recording.URL), WithAnonymousCredentials(),
+		WithDeduplication("blobs/"))
+	if err := s3Fs.WriteFile("rows.json", []byte(`{"a":1}`), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	s3Fs.Select("rows.json", "SELECT * FROM S3Object s", SelectJSON)
+	if !strings.HasPrefix(selected, "/public-sample-data/blobs/") {
+		t.Fatalf("error: expected the content to be queried, got %s", selected)
+	}
+}
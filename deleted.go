@@ -0,0 +1,120 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrDeleted is matched by the *DeletedError of objects whose latest version is a delete marker
+var ErrDeleted = errors.New("s3fs: object is deleted")
+
+// DeletedError is returned with WithDeleteMarkers for a missing object of a versioned bucket
+// whose latest version is a delete marker, so its previous versions still exist. It matches
+// both ErrDeleted and os.ErrNotExist.
+type DeletedError struct {
+	Key string
+	// VersionID is the version ID of the delete marker, the latest version of the key
+	VersionID string
+	// DeletedAt is when the delete marker was created
+	DeletedAt time.Time
+}
+
+func (e *DeletedError) Error() string {
+	return fmt.Sprintf("s3fs: %s was deleted by delete marker %s", e.Key, e.VersionID)
+}
+
+func (e *DeletedError) Is(target error) bool {
+	return target == ErrDeleted || target == os.ErrNotExist
+}
+
+// WithDeleteMarkers makes Open and Stat of missing objects check for a delete marker with a
+// ListObjectVersions, which needs the s3:ListBucketVersions permission, and fail with
+// *DeletedError for deleted objects of versioned buckets. Without it they fail with
+// os.ErrNotExist like for objects that never existed. See also ListOptions.DeleteMarkers.
+func WithDeleteMarkers() Option {
+	return func(f *FileSystem) {
+		f.deleteMarkers = true
+	}
+}
+
+// Undelete restores the deleted object with the name by removing its latest delete marker,
+// which makes the version before it the current object again. It fails with os.ErrNotExist
+// if the latest version of the object isn't a delete marker.
+func (f FileSystem) Undelete(name string) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	err = f.deletedError(ctx, key)
+
+	var deleted *DeletedError
+	if !errors.As(err, &deleted) {
+		return err
+	}
+
+	start := time.Now()
+	_, err = f.s3.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket:    aws.String(f.bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(deleted.VersionID),
+	})
+	f.requestDone(ctx, "DeleteObject", start, err, "key", key, "version", deleted.VersionID)
+
+	return toFSError(err)
+}
+
+// deletedError returns a *DeletedError if the latest version of the key is a delete marker,
+// or else os.ErrNotExist
+func (f FileSystem) deletedError(ctx context.Context, key string) error {
+	// versions are listed by key and then from the latest, so the first version is the
+	// latest version of the key, if it has any
+	list, err := f.listObjectVersions(ctx, &s3.ListObjectVersionsInput{
+		Bucket:  aws.String(f.bucket),
+		Prefix:  aws.String(key),
+		MaxKeys: aws.Int64(1),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, marker := range list.DeleteMarkers {
+		if aws.StringValue(marker.Key) == key && aws.BoolValue(marker.IsLatest) {
+			return &DeletedError{
+				Key:       key,
+				VersionID: aws.StringValue(marker.VersionId),
+				DeletedAt: aws.TimeValue(marker.LastModified),
+			}
+		}
+	}
+	return os.ErrNotExist
+}
+
+// listObjectVersions calls ListObjectVersions and converts the error with toFSError
+func (f FileSystem) listObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	prefix := aws.StringValue(input.Prefix)
+	ctx, span := f.startSpan(ctx, "s3.ListObjectVersions", prefix)
+
+	opts, err := f.intercept(ctx, "ListObjectVersions", input)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	list, err := f.s3.ListObjectVersionsWithContext(ctx, input, opts...)
+	f.requestDone(ctx, "ListObjectVersions", start, err, "prefix", prefix)
+	endSpan(span, err)
+	if err != nil {
+		return nil, toFSError(err)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,100 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestDeleteMarkers(t *testing.T) {
+	srv := newTestServer(t)
+	srv.EnableVersioning("public-sample-data")
+	s3Fs := newTestFileSystem(srv, WithDeleteMarkers())
+
+	if err := s3Fs.WriteFile("notes.txt", []byte("v1"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := s3Fs.WriteFile("notes.txt", []byte("v2"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := s3Fs.Remove("notes.txt"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	_, err := s3Fs.Open("notes.txt")
+	var deleted *DeletedError
+	if !errors.As(err, &deleted) || deleted.Key != "notes.txt" || deleted.VersionID == "" {
+		t.Fatalf("error: expected *DeletedError, got %v", err)
+	}
+	if !errors.Is(err, ErrDeleted) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected the error to match ErrDeleted and os.ErrNotExist")
+	}
+	if _, err := s3Fs.Stat("notes.txt"); !errors.Is(err, ErrDeleted) {
+		t.Fatalf("error: expected Stat to fail with ErrDeleted, got %v", err)
+	}
+	if _, err := s3Fs.Open("missing.txt"); !errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrDeleted) {
+		t.Fatalf("error: expected os.ErrNotExist for an object that never existed, got %v", err)
+	}
+	if _, err := newTestFileSystem(srv).Open("notes.txt"); errors.Is(err, ErrDeleted) {
+		t.Fatalf("error: expected delete markers to be ignored without WithDeleteMarkers")
+	}
+
+	if err := s3Fs.Undelete("notes.txt"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, err := ReadFile(s3Fs, "notes.txt"); err != nil || string(data) != "v2" {
+		t.Fatalf("error: expected the restored version, read %q, %v", data, err)
+	}
+	if err := s3Fs.Undelete("notes.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist undeleting an object that isn't deleted, got %v", err)
+	}
+}
+
+func TestListDeleteMarkers(t *testing.T) {
+	srv := newTestServer(t)
+	srv.EnableVersioning("public-sample-data")
+	s3Fs := newTestFileSystem(srv, WithKeyMapper(PathKeyMapper))
+
+	for _, name := range []string{"docs/a.txt", "docs/b.txt", "docs/c.txt"} {
+		if err := s3Fs.WriteFile(name, []byte(name), ""); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+	s3Fs.WriteFile("docs/a.txt", []byte("a2"), "")
+	s3Fs.Remove("docs/b.txt")
+	s3Fs.Mkdir("docs/img")
+	s3Fs.WriteFile("docs/img/logo.png", []byte("png"), "")
+
+	it := s3Fs.List(context.Background(), ListOptions{Prefix: "docs/", Delimiter: "/", PageSize: 2, DeleteMarkers: true})
+	var got []ObjectInfo
+	for {
+		object, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		got = append(got, object)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("error: expected 4 entries, got %v", got)
+	}
+	if got[0].Key != "docs/a.txt" || got[0].Deleted || got[0].Size != 2 || got[0].VersionID == "" {
+		t.Fatalf("error: expected the latest version of a.txt, got %+v", got[0])
+	}
+	if got[1].Key != "docs/b.txt" || !got[1].Deleted {
+		t.Fatalf("error: expected b.txt to be deleted, got %+v", got[1])
+	}
+	if got[2].Key != "docs/c.txt" || got[2].Deleted || got[3].Key != "docs/img/" || !got[3].IsPrefix {
+		t.Fatalf("error: unexpected entries %+v", got[2:])
+	}
+
+	object, err := s3Fs.List(context.Background(), ListOptions{Prefix: "docs/b"}).Next()
+	if err != io.EOF {
+		t.Fatalf("error: expected deleted keys not to be listed without DeleteMarkers, got %+v, %v", object, err)
+	}
+}
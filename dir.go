@@ -0,0 +1,284 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var errIsDir = errors.New("s3fs: is a directory")
+
+// maxListKeys is the most keys ListObjectsV2 returns at once
+const maxListKeys = 1000
+
+// dirReader lists the entries of a directory File a page of ListObjectsV2 at a time
+type dirReader struct {
+	prefix string
+	// entries are listed but not returned by Readdir yet
+	entries []os.FileInfo
+	// token is the continuation token of the next page
+	token *string
+	done  bool
+}
+
+// openDir opens the directory of key, which exists if there is at least one key under
+// key + "/", including a folder marker "key/" of an empty directory. The root directory,
+// key "", always exists. Directories have no ModTime.
+func (f FileSystem) openDir(ctx context.Context, key string) (*File, error) {
+	prefix := ""
+	if key != "" {
+		prefix = strings.TrimSuffix(key, "/") + "/"
+
+		list, err := f.listObjects(ctx, &s3.ListObjectsV2Input{
+			Bucket:  aws.String(f.bucket),
+			Prefix:  aws.String(prefix),
+			MaxKeys: aws.Int64(1),
+		})
+		if err != nil {
+			return nil, toFSError(err)
+		}
+		if len(list.Contents) == 0 {
+			return nil, os.ErrNotExist
+		}
+	}
+
+	name := path.Base(strings.TrimSuffix(key, "/"))
+	if key == "" {
+		name = "/"
+	}
+
+	return &File{
+		fs:   f,
+		ctx:  ctx,
+		key:  strings.TrimSuffix(key, "/"),
+		stat: fileStat{name: name, isDir: true},
+		dir:  &dirReader{prefix: prefix},
+	}, nil
+}
+
+// readdir implements the os.File contract of Readdir: with count > 0 it returns at most count
+// entries, listing only the pages it needs, and io.EOF once every entry was returned. With
+// count <= 0 it returns every remaining entry.
+func (d *dirReader) readdir(ctx context.Context, f FileSystem, count int) ([]os.FileInfo, error) {
+	for !d.done && (count <= 0 || len(d.entries) < count) {
+		if err := d.next(ctx, f, count); err != nil {
+			if count <= 0 {
+				entries := d.entries
+				d.entries = nil
+				return entries, err
+			}
+			return nil, err
+		}
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := min(count, len(d.entries))
+	entries := d.entries[:n:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// next lists the next page of entries. Pages have up to count keys, the S3 limit of 1000 if
+// count <= 0.
+func (d *dirReader) next(ctx context.Context, f FileSystem, count int) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket:            aws.String(f.bucket),
+		Prefix:            aws.String(d.prefix),
+		Delimiter:         aws.String("/"),
+		ContinuationToken: d.token,
+	}
+	if count > 0 {
+		input.MaxKeys = aws.Int64(int64(min(count, maxListKeys)))
+	}
+
+	list, err := f.listObjects(ctx, input)
+	if err != nil {
+		return toFSError(err)
+	}
+
+	d.entries = append(d.entries, listEntries(d.prefix, list)...)
+	d.token = list.NextContinuationToken
+	d.done = !aws.BoolValue(list.IsTruncated)
+	return nil
+}
+
+// listEntries returns the common prefixes of a listing as directories and its objects as files
+func listEntries(prefix string, list *s3.ListObjectsV2Output) []os.FileInfo {
+	entries := make([]os.FileInfo, 0, len(list.CommonPrefixes)+len(list.Contents))
+
+	for _, p := range list.CommonPrefixes {
+		name := strings.TrimSuffix(strings.TrimPrefix(aws.StringValue(p.Prefix), prefix), "/")
+		entries = append(entries, fileStat{name: name, isDir: true})
+	}
+
+	for _, object := range list.Contents {
+		key := aws.StringValue(object.Key)
+		if key == prefix {
+			// the folder marker of the directory itself
+			continue
+		}
+		if strings.HasSuffix(key, "/") {
+			entries = append(entries, fileStat{name: strings.TrimSuffix(strings.TrimPrefix(key, prefix), "/"), isDir: true})
+			continue
+		}
+
+		size := aws.Int64Value(object.Size)
+		entries = append(entries, fileStat{
+			name:         strings.TrimPrefix(key, prefix),
+			size:         size,
+			totalSize:    size,
+			modTime:      aws.TimeValue(object.LastModified),
+			storageClass: aws.StringValue(object.StorageClass),
+		})
+	}
+
+	return entries
+}
+
+// Stat returns the FileInfo of the object or directory with the name without downloading it
+func (f FileSystem) Stat(name string) (os.FileInfo, error) {
+	return f.StatContext(context.Background(), name)
+}
+
+// StatContext is like Stat. Objects are found with HeadObject and directories by listing.
+func (f FileSystem) StatContext(ctx context.Context, name string) (os.FileInfo, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" || strings.HasSuffix(key, "/") {
+		return f.statDir(ctx, key)
+	}
+
+	object, err := f.headObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if fi, ok := f.statStaged(key, object, err); ok {
+		return fi, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		fi, err := f.statDir(ctx, key)
+		if errors.Is(err, os.ErrNotExist) && f.deleteMarkers {
+			return nil, f.deletedError(ctx, key)
+		}
+		return fi, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ref, ok := contentRef(object.Metadata, aws.Int64Value(object.ContentLength)); ok {
+		if object, err = f.headRef(ctx, ref, object); err != nil {
+			return nil, err
+		}
+	}
+
+	size := aws.Int64Value(object.ContentLength)
+	if f.encryption != nil && isEncrypted(object.Metadata) {
+		size = plainSize(size)
+	}
+	return fileStat{
+		name:         path.Base(key),
+		size:         size,
+		totalSize:    size,
+		modTime:      aws.TimeValue(object.LastModified),
+		storageClass: aws.StringValue(object.StorageClass),
+	}, nil
+}
+
+func (f FileSystem) statDir(ctx context.Context, key string) (os.FileInfo, error) {
+	dir, err := f.openDir(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return dir.stat, nil
+}
+
+// Mkdir creates the directory with the name. With WithFolderMarkers it puts a zero-byte
+// object with the key of the directory and a trailing slash; otherwise directories are
+// implicit in the keys of their objects and Mkdir does nothing.
+func (f FileSystem) Mkdir(name string) error {
+	if !f.folderMarkers {
+		return nil
+	}
+
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	key = strings.TrimSuffix(key, "/")
+	if key == "" || key == "." {
+		return nil
+	}
+
+	_, err = f.putObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(f.bucket),
+		Key:         aws.String(key + "/"),
+		Body:        bytes.NewReader(nil),
+		ContentType: aws.String("application/x-directory"),
+	})
+	return err
+}
+
+// ReadDir is like Readdir and makes File an fs.ReadDirFile
+func (f *File) ReadDir(count int) ([]fs.DirEntry, error) {
+	infos, err := f.Readdir(count)
+
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+
+	return entries, err
+}
+
+// FS returns the FileSystem as an fs.FS, so that it can be used with fs.WalkDir, fs.Glob
+// and the like. Names are slash-separated paths without a leading slash as required by fs.FS
+// and are mapped to keys with the KeyMapper, so PathKeyMapper gives the expected behavior.
+// Index and SPA fallback options don't apply to the fs.FS.
+func (f FileSystem) FS() fs.FS {
+	return ioFS{f}
+}
+
+type ioFS struct {
+	fs FileSystem
+}
+
+func (i ioFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		name = ""
+	}
+
+	file, err := i.fs.openKey(context.Background(), "/"+name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return file, nil
+}
+
+var _ fs.ReadDirFile = (*File)(nil)
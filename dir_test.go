@@ -0,0 +1,142 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestListEntries(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	list := &s3.ListObjectsV2Output{
+		CommonPrefixes: []*s3.CommonPrefix{{Prefix: aws.String("docs/images/")}},
+		Contents: []*s3.Object{
+			{Key: aws.String("docs/"), Size: aws.Int64(0)},
+			{Key: aws.String("docs/empty/"), Size: aws.Int64(0)},
+			{Key: aws.String("docs/setup.html"), Size: aws.Int64(42), LastModified: aws.Time(modTime)},
+		},
+	}
+
+	entries := listEntries("docs/", list)
+	if len(entries) != 3 {
+		t.Fatalf("error: expected 3 entries, got %d", len(entries))
+	}
+
+	dir, marker, file := entries[0], entries[1], entries[2]
+	if marker.Name() != "empty" || !marker.IsDir() {
+		t.Fatalf("error: expected folder marker to be listed as directory empty, got %s", marker.Name())
+	}
+	if dir.Name() != "images" || !dir.IsDir() || !dir.Mode().IsDir() || !dir.ModTime().IsZero() {
+		t.Fatalf("error: unexpected directory entry %s %v %v", dir.Name(), dir.Mode(), dir.ModTime())
+	}
+	if file.Name() != "setup.html" || file.IsDir() || file.Size() != 42 || !file.ModTime().Equal(modTime) {
+		t.Fatalf("error: unexpected file entry %s %d %v", file.Name(), file.Size(), file.ModTime())
+	}
+}
+
+func TestReaddirCount(t *testing.T) {
+	d := &dirReader{done: true, entries: []fs.FileInfo{
+		fileStat{name: "a"}, fileStat{name: "b"}, fileStat{name: "c"},
+	}}
+	f := &File{stat: fileStat{name: "/", isDir: true}, dir: d}
+
+	entries, err := f.Readdir(2)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("error: expected 2 entries, got %d, %v", len(entries), err)
+	}
+
+	dirEntries, err := f.ReadDir(2)
+	if err != nil || len(dirEntries) != 1 || dirEntries[0].Name() != "c" {
+		t.Fatalf("error: expected entry c, got %v, %v", dirEntries, err)
+	}
+
+	if _, err := f.Readdir(2); err != io.EOF {
+		t.Fatalf("error: expected io.EOF, got %v", err)
+	}
+
+	if _, err := f.Read(make([]byte, 1)); err != errIsDir {
+		t.Fatalf("error: expected errIsDir reading a directory, got %v", err)
+	}
+}
+
+func TestReaddirPages(t *testing.T) {
+	srv := newTestServer(t)
+	for i := range 5 {
+		srv.PutObject("public-sample-data", fmt.Sprintf("logs/%d.log", i), []byte("log"), "")
+	}
+
+	lists := 0
+	s3Fs := newTestFileSystem(srv, WithKeyMapper(PathKeyMapper),
+		WithRequestInterceptor(func(ctx context.Context, r *Request) error {
+			if r.Operation == "ListObjectsV2" {
+				lists++
+			}
+			return nil
+		}))
+
+	dir, err := s3Fs.Open("/logs")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	lists = 0
+
+	var names []string
+	for _, want := range []int{2, 2, 1} {
+		entries, err := dir.Readdir(2)
+		if err != nil || len(entries) != want {
+			t.Fatalf("error: expected %d entries, got %d, %v", want, len(entries), err)
+		}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		if lists != len(names)/2+len(names)%2 {
+			t.Fatalf("error: expected a ListObjectsV2 per Readdir, got %d", lists)
+		}
+	}
+	if _, err := dir.Readdir(2); err != io.EOF {
+		t.Fatalf("error: expected io.EOF, got %v", err)
+	}
+	if fmt.Sprint(names) != "[0.log 1.log 2.log 3.log 4.log]" {
+		t.Fatalf("error: unexpected entries %v", names)
+	}
+
+	dir, _ = s3Fs.Open("/logs")
+	if entries, err := dir.Readdir(3); err != nil || len(entries) != 3 {
+		t.Fatalf("error: expected 3 entries, got %d, %v", len(entries), err)
+	}
+	if entries, err := dir.Readdir(-1); err != nil || len(entries) != 2 || entries[0].Name() != "3.log" {
+		t.Fatalf("error: expected the remaining entries, got %v, %v", entries, err)
+	}
+	if entries, err := dir.Readdir(-1); err != nil || len(entries) != 0 {
+		t.Fatalf("error: expected no entries at the end, got %v, %v", entries, err)
+	}
+}
+
+func TestFSInvalidPath(t *testing.T) {
+	fsys := New("public-sample-data", "us-east-1").FS()
+
+	for _, name := range []string{"/index.html", "docs/", "../secret"} {
+		if _, err := fsys.Open(name); !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("error: expected fs.ErrInvalid opening %s, got %v", name, err)
+		}
+	}
+}
+
+func TestMkdirWithoutFolderMarkers(t *testing.T) {
+	var names []string
+	s3Fs := New("public-sample-data", "us-east-1", WithKeyMapper(recordingKeyMapper(&names)))
+
+	if err := s3Fs.Mkdir("/docs"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("error: expected Mkdir to do nothing, mapped %v", names)
+	}
+}
@@ -0,0 +1,301 @@
+package s3fs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	defaultDownloadChunkSize   = 8 << 20
+	defaultDownloadConcurrency = 4
+)
+
+// DownloadOptions configures DownloadTo
+type DownloadOptions struct {
+	// StateFile is where the progress of the download is saved. If it is set, an interrupted
+	// download is resumed from the chunks saved in StateFile when DownloadTo is called again,
+	// as long as the object has the same ETag. StateFile is removed when the download completes.
+	StateFile string
+	// ChunkSize is the size of the ranges downloaded in parallel, 8MB if it is zero
+	ChunkSize int64
+	// Concurrency is the number of ranges downloaded in parallel, 4 if it is zero
+	Concurrency int
+}
+
+// downloadState is the progress of a download saved in DownloadOptions.StateFile
+type downloadState struct {
+	Key       string     `json:"key"`
+	ETag      string     `json:"etag"`
+	Size      int64      `json:"size"`
+	Completed [][2]int64 `json:"completed"`
+}
+
+// DownloadTo downloads the object with the name to the file localPath using parallel ranged
+// GetObjects. Every range is conditional on the ETag of the object, so the download fails
+// with ErrObjectChanged rather than mixing two versions of an object overwritten meanwhile.
+// The modification time of the file is set to the LastModified of the object. With WithProgress
+// the bytes downloaded by all ranges are reported together. With WithEncryption encrypted
+// objects are decrypted as they are downloaded.
+func (f FileSystem) DownloadTo(ctx context.Context, name, localPath string, opts DownloadOptions) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultDownloadChunkSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultDownloadConcurrency
+	}
+
+	source, head, err := f.headContent(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	state := &downloadState{
+		Key:  key,
+		ETag: aws.StringValue(head.ETag),
+		Size: aws.Int64Value(head.ContentLength),
+	}
+	enc, err := f.openObjectCipher(ctx, head.Metadata, state.Size)
+	if err != nil {
+		return err
+	}
+	if enc != nil {
+		state.Size = plainSize(state.Size)
+	}
+
+	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if opts.StateFile != "" {
+		if saved, err := loadDownloadState(opts.StateFile); err == nil &&
+			saved.Key == state.Key && saved.ETag == state.ETag && saved.Size == state.Size {
+			state = saved
+			flags &^= os.O_TRUNC
+		}
+	}
+
+	file, err := os.OpenFile(localPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := file.Truncate(state.Size); err != nil {
+		return err
+	}
+
+	d := &download{
+		fs:        f,
+		key:       source,
+		file:      file,
+		state:     state,
+		stateFile: opts.StateFile,
+		cipher:    enc,
+		progress:  f.newProgress(state.Size, completedSize(state.Completed)),
+	}
+	if err := d.run(ctx, opts); err != nil {
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if opts.StateFile != "" {
+		if err := os.Remove(opts.StateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	modTime := aws.TimeValue(head.LastModified)
+	return os.Chtimes(localPath, modTime, modTime)
+}
+
+// download is a DownloadTo in progress
+type download struct {
+	fs FileSystem
+	// key holds the data of the object, the content of pointer objects
+	key       string
+	file      *os.File
+	stateFile string
+	progress  *progress
+	// cipher decrypts the chunks of encrypted objects
+	cipher *objectCipher
+
+	mu    sync.Mutex
+	state *downloadState
+}
+
+// run downloads every range of the object that isn't completed yet
+func (d *download) run(ctx context.Context, opts DownloadOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks := make(chan [2]int64)
+	errs := make(chan error, opts.Concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				if err := d.fetch(ctx, chunk); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+loop:
+	for start := int64(0); start < d.state.Size; start += opts.ChunkSize {
+		chunk := [2]int64{start, min(start+opts.ChunkSize, d.state.Size) - 1}
+		if rangeCovered(d.state.Completed, chunk) {
+			continue
+		}
+
+		select {
+		case chunks <- chunk:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(chunks)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// fetch downloads chunk into the file and saves it as completed
+func (d *download) fetch(ctx context.Context, chunk [2]int64) error {
+	length := chunk[1] - chunk[0] + 1
+	start, end := chunk[0], chunk[1]
+	if d.cipher != nil {
+		start, end = d.cipher.cipherRange(chunk[0], length)
+	}
+
+	object, err := d.fs.getObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(d.fs.bucket),
+		Key:     aws.String(d.key),
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		IfMatch: aws.String(d.state.ETag),
+	})
+	if err != nil {
+		return err
+	}
+
+	data := object.Body
+	if d.cipher != nil {
+		data = d.cipher.decrypt(data, chunk[0])
+	}
+	defer data.Close()
+
+	body := progressReader{r: data, p: d.progress}
+	if _, err := io.CopyN(io.NewOffsetWriter(d.file, chunk[0]), body, length); err != nil {
+		return err
+	}
+
+	if d.stateFile == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the chunk must be on disk before the state says it's completed
+	if err := d.file.Sync(); err != nil {
+		return err
+	}
+
+	d.state.Completed = addRange(d.state.Completed, chunk)
+	return saveDownloadState(d.stateFile, d.state)
+}
+
+func loadDownloadState(path string) (*downloadState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &downloadState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// saveDownloadState replaces the state file atomically, so an interruption while saving
+// leaves the previous state
+func saveDownloadState(path string, state *downloadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// addRange adds the inclusive range r to the sorted, non-overlapping ranges, merging
+// ranges that overlap or touch
+func addRange(ranges [][2]int64, r [2]int64) [][2]int64 {
+	ranges = append(ranges, r)
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+
+	merged := ranges[:1]
+	for _, next := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if next[0] <= last[1]+1 {
+			last[1] = max(last[1], next[1])
+			continue
+		}
+		merged = append(merged, next)
+	}
+
+	return merged
+}
+
+// completedSize returns the number of bytes in ranges
+func completedSize(ranges [][2]int64) int64 {
+	var n int64
+	for _, r := range ranges {
+		n += r[1] - r[0] + 1
+	}
+	return n
+}
+
+// rangeCovered reports whether the inclusive range r is inside one of ranges
+func rangeCovered(ranges [][2]int64, r [2]int64) bool {
+	for _, c := range ranges {
+		if c[0] <= r[0] && r[1] <= c[1] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package s3fs
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAddRange(t *testing.T) {
+	var ranges [][2]int64
+	for _, r := range [][2]int64{{20, 29}, {0, 9}, {40, 49}, {10, 19}} {
+		ranges = addRange(ranges, r)
+	}
+
+	want := [][2]int64{{0, 29}, {40, 49}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("error: merged ranges are %v, want %v", ranges, want)
+	}
+
+	if !rangeCovered(ranges, [2]int64{10, 19}) || rangeCovered(ranges, [2]int64{25, 44}) || rangeCovered(ranges, [2]int64{50, 59}) {
+		t.Fatalf("error: unexpected coverage of %v", ranges)
+	}
+}
+
+func TestDownloadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wikipedia-20150518.bin.s3fs-download")
+	state := &downloadState{
+		Key:       "wikipedia-20150518.bin",
+		ETag:      `"9b2cf535f27731c974343645a3985328-2400"`,
+		Size:      20 << 30,
+		Completed: [][2]int64{{0, 8<<20 - 1}},
+	}
+
+	if err := saveDownloadState(path, state); err != nil {
+		t.Fatalf("error: saving state: %s", err)
+	}
+
+	loaded, err := loadDownloadState(path)
+	if err != nil {
+		t.Fatalf("error: loading state: %s", err)
+	}
+	if !reflect.DeepEqual(loaded, state) {
+		t.Fatalf("error: loaded %+v, want %+v", loaded, state)
+	}
+}
@@ -0,0 +1,371 @@
+package s3fs
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+const (
+	// encryptChunkSize is the size of the plaintext chunks sealed separately, so that ranges
+	// of an encrypted object can be decrypted and authenticated without reading all of it
+	encryptChunkSize = 64 << 10
+	// encryptTagSize is the size of the GCM tag of every chunk
+	encryptTagSize = 16
+
+	// metaEncryptedKey and metaNonce are the user metadata of encrypted objects holding
+	// the wrapped data key and the nonce of the first chunk
+	metaEncryptedKey = "S3fs-Encrypted-Key"
+	metaNonce        = "S3fs-Nonce"
+)
+
+var (
+	// ErrDecrypt is returned when an encrypted object or its data key fails authentication
+	ErrDecrypt = errors.New("s3fs: decrypting object failed")
+	// ErrEncryptedAppend is returned by OpenAppend for encrypted objects and by the OpenAppend
+	// of a FileSystem with WithEncryption
+	ErrEncryptedAppend = errors.New("s3fs: can't append to an encrypted object")
+	// ErrEncryptedRange is returned by the Open of FileSystemWithRanges for encrypted objects,
+	// whose ranges can only be read with FileSystem
+	ErrEncryptedRange = errors.New("s3fs: can't open a range of an encrypted object")
+)
+
+// KeyWrapper encrypts the data keys of objects encrypted with WithEncryption
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// WithEncryption encrypts objects on the client before they are uploaded by WriteFile and
+// Create, and decrypts them on Open, for buckets where server-side encryption isn't enough.
+// Every object is encrypted with its own AES-256 data key using AES-GCM in 64KB chunks, so
+// ranges can be read and authenticated on their own, and the data key is stored in the user
+// metadata of the object, wrapped by w. Objects without a data key are read as they are.
+// Listings report the encrypted size of objects; Open and Stat report the decrypted size.
+func WithEncryption(w KeyWrapper) Option {
+	return func(f *FileSystem) {
+		f.encryption = w
+	}
+}
+
+// localKeyWrapper wraps data keys with a master key held by the application
+type localKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKeyWrapper returns a KeyWrapper wrapping data keys with AES-256-GCM using the
+// 32-byte masterKey
+func NewLocalKeyWrapper(masterKey []byte) (KeyWrapper, error) {
+	if len(masterKey) != 32 {
+		return nil, errors.New("s3fs: master key must be 32 bytes")
+	}
+
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return localKeyWrapper{aead: aead}, nil
+}
+
+func (w localKeyWrapper) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return w.aead.Seal(nonce, nonce, key, []byte("s3fs data key")), nil
+}
+
+func (w localKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < w.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, sealed := wrapped[:w.aead.NonceSize()], wrapped[w.aead.NonceSize():]
+	key, err := w.aead.Open(nil, nonce, sealed, []byte("s3fs data key"))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return key, nil
+}
+
+// kmsKeyWrapper wraps data keys with a KMS key
+type kmsKeyWrapper struct {
+	client kmsiface.KMSAPI
+	keyID  string
+}
+
+// kmsEncryptionContext binds the wrapped keys to their use, and must be the same for Decrypt
+var kmsEncryptionContext = map[string]*string{"s3fs": aws.String("data-key")}
+
+// NewKMSKeyWrapper returns a KeyWrapper wrapping data keys with Encrypt and Decrypt of the
+// KMS key keyID, which can be a key ID, a key ARN or an alias
+func NewKMSKeyWrapper(client kmsiface.KMSAPI, keyID string) KeyWrapper {
+	return kmsKeyWrapper{client: client, keyID: keyID}
+}
+
+func (w kmsKeyWrapper) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	out, err := w.client.EncryptWithContext(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(w.keyID),
+		Plaintext:         key,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.CiphertextBlob, nil
+}
+
+func (w kmsKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	out, err := w.client.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(w.keyID),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// objectCipher encrypts or decrypts the chunks of one object
+type objectCipher struct {
+	aead  cipher.AEAD
+	nonce []byte
+	// size is the encrypted size of the object, known when decrypting
+	size int64
+}
+
+// newObjectCipher creates the cipher of a new object and the metadata to store with it
+func (f FileSystem) newObjectCipher(ctx context.Context) (*objectCipher, map[string]*string, error) {
+	key := make([]byte, 32)
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	wrapped, err := f.encryption.WrapKey(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata := map[string]*string{
+		metaEncryptedKey: aws.String(base64.StdEncoding.EncodeToString(wrapped)),
+		metaNonce:        aws.String(base64.StdEncoding.EncodeToString(nonce)),
+	}
+	return &objectCipher{aead: aead, nonce: nonce}, metadata, nil
+}
+
+// openObjectCipher returns the cipher of an encrypted object of size bytes, or nil if the
+// object isn't encrypted or there is no KeyWrapper
+func (f FileSystem) openObjectCipher(ctx context.Context, metadata map[string]*string, size int64) (*objectCipher, error) {
+	wrappedText, ok := metadataValue(metadata, metaEncryptedKey)
+	if !ok || f.encryption == nil {
+		return nil, nil
+	}
+
+	nonceText, _ := metadataValue(metadata, metaNonce)
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedText)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	nonce, err := base64.StdEncoding.DecodeString(nonceText)
+	if err != nil || len(nonce) != 12 || size < encryptTagSize {
+		return nil, ErrDecrypt
+	}
+
+	key, err := f.encryption.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return &objectCipher{aead: aead, nonce: nonce, size: size}, nil
+}
+
+// isEncrypted reports whether the metadata of an object has a data key
+func isEncrypted(metadata map[string]*string) bool {
+	_, ok := metadataValue(metadata, metaEncryptedKey)
+	return ok
+}
+
+// metadataValue looks up user metadata case-insensitively, since S3 lowercases the names
+func metadataValue(metadata map[string]*string, name string) (string, bool) {
+	for k, v := range metadata {
+		if strings.EqualFold(k, name) {
+			return aws.StringValue(v), true
+		}
+	}
+	return "", false
+}
+
+// plainSize returns the decrypted size of an encrypted object of size bytes
+func plainSize(size int64) int64 {
+	chunks := (size + encryptChunkSize + encryptTagSize - 1) / (encryptChunkSize + encryptTagSize)
+	return size - chunks*encryptTagSize
+}
+
+func (c *objectCipher) chunks() int64 {
+	return (c.size + encryptChunkSize + encryptTagSize - 1) / (encryptChunkSize + encryptTagSize)
+}
+
+// chunkSize returns the encrypted size of chunk i, or 0 past the last chunk
+func (c *objectCipher) chunkSize(i int64) int64 {
+	switch chunks := c.chunks(); {
+	case i >= chunks:
+		return 0
+	case i == chunks-1:
+		return c.size - i*(encryptChunkSize+encryptTagSize)
+	}
+	return encryptChunkSize + encryptTagSize
+}
+
+// cipherRange returns the inclusive range of the encrypted chunks holding the n decrypted
+// bytes starting at off
+func (c *objectCipher) cipherRange(off, n int64) (int64, int64) {
+	first, last := off/encryptChunkSize, (off+n-1)/encryptChunkSize
+	end := min((last+1)*(encryptChunkSize+encryptTagSize), c.size)
+	return first * (encryptChunkSize + encryptTagSize), end - 1
+}
+
+// chunkNonce and chunkData make every chunk authenticate its position and whether it is
+// the last one, so chunks can't be reordered and the object can't be truncated
+func (c *objectCipher) chunkNonce(i int64) []byte {
+	nonce := append([]byte(nil), c.nonce...)
+	binary.BigEndian.PutUint64(nonce[4:], binary.BigEndian.Uint64(nonce[4:])^uint64(i))
+	return nonce
+}
+
+func chunkData(i int64, last bool) []byte {
+	data := binary.BigEndian.AppendUint64([]byte("s3fs"), uint64(i))
+	if last {
+		return append(data, 1)
+	}
+	return append(data, 0)
+}
+
+func (c *objectCipher) seal(dst []byte, i int64, last bool, plain []byte) []byte {
+	return c.aead.Seal(dst, c.chunkNonce(i), plain, chunkData(i, last))
+}
+
+func (c *objectCipher) open(i int64, sealed []byte) ([]byte, error) {
+	plain, err := c.aead.Open(sealed[:0], c.chunkNonce(i), sealed, chunkData(i, i == c.chunks()-1))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// encrypt seals the whole plaintext of an object
+func (c *objectCipher) encrypt(plain []byte) []byte {
+	sealed := make([]byte, 0, len(plain)+(len(plain)/encryptChunkSize+1)*encryptTagSize)
+	w := &chunkSealer{c: c}
+	sealed = append(sealed, w.write(plain)...)
+	return append(sealed, w.close()...)
+}
+
+// decrypt returns a body decrypting the encrypted chunks of body, which start with the chunk
+// of the decrypted offset off
+func (c *objectCipher) decrypt(body io.ReadCloser, off int64) io.ReadCloser {
+	return &decryptingBody{c: c, body: body, chunk: off / encryptChunkSize, skip: off % encryptChunkSize}
+}
+
+type decryptingBody struct {
+	c     *objectCipher
+	body  io.ReadCloser
+	chunk int64
+	skip  int64
+	buf   []byte
+	plain []byte
+}
+
+func (d *decryptingBody) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		size := d.c.chunkSize(d.chunk)
+		if size == 0 {
+			return 0, io.EOF
+		}
+
+		if int64(cap(d.buf)) < size {
+			d.buf = make([]byte, size)
+		}
+		if _, err := io.ReadFull(d.body, d.buf[:size]); err != nil {
+			// a ranged body ends at the end of a chunk
+			return 0, err
+		}
+
+		plain, err := d.c.open(d.chunk, d.buf[:size])
+		if err != nil {
+			return 0, err
+		}
+		d.chunk++
+		d.plain, d.skip = plain[d.skip:], 0
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptingBody) Close() error {
+	return d.body.Close()
+}
+
+// chunkSealer encrypts a stream of plaintext. It holds back the last chunk until close,
+// since the last chunk is sealed differently.
+type chunkSealer struct {
+	c     *objectCipher
+	chunk int64
+	buf   []byte
+}
+
+// write returns the chunks sealed with the data of p
+func (s *chunkSealer) write(p []byte) []byte {
+	s.buf = append(s.buf, p...)
+
+	var sealed []byte
+	for len(s.buf) > encryptChunkSize {
+		sealed = s.c.seal(sealed, s.chunk, false, s.buf[:encryptChunkSize])
+		s.buf = s.buf[encryptChunkSize:]
+		s.chunk++
+	}
+	return sealed
+}
+
+// close returns the last chunk
+func (s *chunkSealer) close() []byte {
+	sealed := s.c.seal(nil, s.chunk, true, s.buf)
+	s.buf = nil
+	return sealed
+}
@@ -0,0 +1,198 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/shijuleon/s3fs/s3test"
+)
+
+func newEncryptedFileSystem(t *testing.T, srv *s3test.Server) *FileSystem {
+	w, err := NewLocalKeyWrapper(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return newTestFileSystem(srv, WithEncryption(w))
+}
+
+func TestEncryption(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newEncryptedFileSystem(t, srv)
+
+	data := make([]byte, 3*encryptChunkSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	if err := s3Fs.WriteFile("written.bin", data, ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	w, err := s3Fs.Create("created.bin")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	for chunk := data; len(chunk) > 0; chunk = chunk[min(len(chunk), 10000):] {
+		if _, err := w.Write(chunk[:min(len(chunk), 10000)]); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	for _, name := range []string{"written.bin", "created.bin"} {
+		stored, _ := srv.Object("public-sample-data", name)
+		if bytes.Contains(stored, data[:1000]) {
+			t.Fatalf("error: %s is stored in plaintext", name)
+		}
+
+		got, err := ReadFile(s3Fs, name)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("error: %s doesn't decrypt to the written data", name)
+		}
+
+		fi, err := s3Fs.Stat(name)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if fi.Size() != int64(len(data)) {
+			t.Fatalf("error: expected size %d, got %d", len(data), fi.Size())
+		}
+
+		f, err := s3Fs.Open(name)
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		file := f.(*File)
+
+		// across the boundary between the first and second chunks
+		off := int64(encryptChunkSize - 100)
+		if _, err := file.Seek(off, io.SeekStart); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		buf := make([]byte, 200)
+		if _, err := io.ReadFull(file, buf); err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		if !bytes.Equal(buf, data[off:off+200]) {
+			t.Fatalf("error: unexpected data after Seek")
+		}
+
+		off = int64(len(data) - 500)
+		n, err := file.ReadAt(buf, off)
+		if n != 200 || (err != nil && err != io.EOF) {
+			t.Fatalf("error: ReadAt returned %d, %v", n, err)
+		}
+		if !bytes.Equal(buf, data[off:off+200]) {
+			t.Fatalf("error: unexpected data from ReadAt")
+		}
+		file.Close()
+	}
+}
+
+func TestEncryptionTampered(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newEncryptedFileSystem(t, srv)
+
+	if err := s3Fs.WriteFile("secret.txt", []byte("attack at dawn"), "text/plain"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	head, err := s3Fs.s3.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String("public-sample-data"),
+		Key:    aws.String("secret.txt"),
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	stored, _ := srv.Object("public-sample-data", "secret.txt")
+	tampered := bytes.Clone(stored)
+	tampered[0] ^= 1
+	_, err = s3Fs.s3.PutObject(&s3.PutObjectInput{
+		Bucket:   aws.String("public-sample-data"),
+		Key:      aws.String("secret.txt"),
+		Body:     bytes.NewReader(tampered),
+		Metadata: head.Metadata,
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if _, err := ReadFile(s3Fs, "secret.txt"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("error: expected ErrDecrypt, got %v", err)
+	}
+
+	if _, err := s3Fs.OpenAppend("secret.txt"); !errors.Is(err, ErrEncryptedAppend) {
+		t.Fatalf("error: expected ErrEncryptedAppend, got %v", err)
+	}
+}
+
+func TestEncryptionRanges(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newEncryptedFileSystem(t, srv)
+
+	data := make([]byte, 3*encryptChunkSize+1000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	if err := s3Fs.WriteFile("secret.bin", data, ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	m, err := s3Fs.OpenRanges("secret.bin", [][2]int64{{10, 20}, {encryptChunkSize - 5, 2*encryptChunkSize + 5}, {int64(len(data)) - 10, int64(len(data)) + 100}})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if m.Size() != int64(len(data)) {
+		t.Fatalf("error: expected the decrypted size %d, got %d", len(data), m.Size())
+	}
+
+	var buf bytes.Buffer
+	enc := m.NewEncoder(&buf)
+	if err := m.Encode(enc); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	enc.Close()
+
+	_, params, _ := mime.ParseMediaType(enc.ContentType())
+	mr := multipart.NewReader(&buf, params["boundary"])
+	for _, r := range m.Ranges() {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		got, _ := io.ReadAll(part)
+		if !bytes.Equal(got, data[r.Start():r.End()+1]) {
+			t.Fatalf("error: range %v doesn't decrypt to the written data", r)
+		}
+	}
+
+	local := filepath.Join(t.TempDir(), "secret.bin")
+	if err := s3Fs.DownloadTo(context.Background(), "secret.bin", local, DownloadOptions{ChunkSize: encryptChunkSize + 7}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if got, _ := os.ReadFile(local); !bytes.Equal(got, data) {
+		t.Fatalf("error: DownloadTo wrote %d bytes that don't decrypt to the written data", len(got))
+	}
+
+	w, _ := NewLocalKeyWrapper(bytes.Repeat([]byte("k"), 32))
+	ranged := NewWithRange("public-sample-data", "us-east-1", NewFileRanges(0, 99),
+		WithEndpoint(srv.URL), WithAnonymousCredentials(), WithEncryption(w))
+	if _, err := ranged.Open("secret.bin"); !errors.Is(err, ErrEncryptedRange) {
+		t.Fatalf("error: expected ErrEncryptedRange, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// FailoverOptions configures FailoverFS
+type FailoverOptions struct {
+	// OpenTimeout starts opening the object from the secondary if the primary hasn't answered
+	// within it, and the first of the two to succeed is used. If it is zero, the secondary is
+	// only used when the primary fails.
+	OpenTimeout time.Duration
+}
+
+// FailoverFS implements ContextFileSystem by opening files from a primary FileSystem and,
+// when it fails, from a secondary one, such as the replica of a bucket in another region.
+// Reads of a File opened from the primary that fail midway continue from the secondary at the
+// same offset. Missing objects, changed objects and canceled contexts don't fail over. The
+// object of the secondary must have the same ETag, as replicas do unless they are encrypted
+// with SSE-KMS, or reads from it fail with ErrObjectChanged.
+type FailoverFS struct {
+	primary   *FileSystem
+	secondary *FileSystem
+	opts      FailoverOptions
+}
+
+// NewFailoverFS creates a FailoverFS
+func NewFailoverFS(primary, secondary *FileSystem, opts FailoverOptions) *FailoverFS {
+	return &FailoverFS{primary: primary, secondary: secondary, opts: opts}
+}
+
+// Open opens name from the primary or the secondary FileSystem
+func (f *FailoverFS) Open(name string) (http.File, error) {
+	return f.OpenContext(context.Background(), name)
+}
+
+type failoverResult struct {
+	fi      *File
+	err     error
+	primary bool
+}
+
+// OpenContext is like Open
+func (f *FailoverFS) OpenContext(ctx context.Context, name string) (http.File, error) {
+	results := make(chan failoverResult, 2)
+	open := func(fs *FileSystem, primary bool) {
+		fi, err := fs.openFile(ctx, name)
+		results <- failoverResult{fi: fi, err: err, primary: primary}
+	}
+
+	go open(f.primary, true)
+	pending, secondaryStarted := 1, false
+	startSecondary := func() {
+		secondaryStarted = true
+		pending++
+		go open(f.secondary, false)
+	}
+
+	var timeout <-chan time.Time
+	if f.opts.OpenTimeout > 0 {
+		timer := time.NewTimer(f.opts.OpenTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var primaryErr error
+	for {
+		select {
+		case <-timeout:
+			timeout = nil
+			if !secondaryStarted {
+				f.primary.logger.Log(ctx, slog.LevelWarn, "s3fs: primary is slow, opening from secondary", "name", name)
+				startSecondary()
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if pending > 0 {
+					go closeFailoverResult(results)
+				}
+				if r.primary {
+					r.fi.failover = f.secondary
+				}
+				return r.fi, nil
+			}
+
+			if r.primary {
+				primaryErr = r.err
+				if !canFailover(ctx, r.err) {
+					// the secondary started by OpenTimeout would serve a deleted or
+					// stale replica
+					if pending > 0 {
+						go closeFailoverResult(results)
+					}
+					return nil, r.err
+				}
+				if !secondaryStarted {
+					f.primary.logger.Log(ctx, slog.LevelWarn, "s3fs: opening from secondary", "name", name, "error", r.err)
+					startSecondary()
+				}
+			}
+			if pending == 0 {
+				if primaryErr != nil && !canFailover(ctx, primaryErr) {
+					return nil, primaryErr
+				}
+				return nil, r.err
+			}
+		}
+	}
+}
+
+// closeFailoverResult closes the File of the Open that lost
+func closeFailoverResult(results <-chan failoverResult) {
+	if r := <-results; r.fi != nil {
+		r.fi.Close()
+	}
+}
+
+// canFailover reports whether the secondary may succeed where err failed
+func canFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	return !errors.Is(err, os.ErrNotExist) && !errors.Is(err, ErrObjectChanged) &&
+		!errors.Is(err, errIsDir) && !errors.Is(err, context.Canceled)
+}
+
+// failOver makes the File read from the secondary FileSystem after a Read failed with err
+func (f *File) failOver(err error) {
+	f.fs.logger.Log(f.ctx, slog.LevelWarn, "s3fs: reading from secondary", "key", f.key, "offset", f.offset, "error", err)
+
+	if f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.fs = *f.failover
+	f.failover = nil
+}
@@ -0,0 +1,131 @@
+package s3fs
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// cutWriter aborts the response after limit bytes of the body
+type cutWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *cutWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		w.ResponseWriter.Write(p[:w.limit])
+		w.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// newFailoverServers returns a primary whose GetObjects are changed by wrap and a
+// secondary with the same objects
+func newFailoverServers(t *testing.T, wrap func(w http.ResponseWriter, r *http.Request, next http.Handler)) (*FileSystem, *FileSystem) {
+	srv := newTestServer(t)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrap(w, r, srv.Config.Handler)
+	}))
+	t.Cleanup(func() {
+		primary.CloseClientConnections()
+		primary.Close()
+	})
+
+	return New("public-sample-data", "us-east-1", WithEndpoint(primary.URL), WithAnonymousCredentials()),
+		newTestFileSystem(srv)
+}
+
+func TestFailoverOpen(t *testing.T) {
+	primary, secondary := newFailoverServers(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		http.Error(w, "denied", http.StatusForbidden)
+	})
+	fsys := NewFailoverFS(primary, secondary, FailoverOptions{})
+
+	data, err := ReadFile(fsys, "passengers.txt")
+	if err != nil || len(data) != 1046 {
+		t.Fatalf("error: read %d bytes, %v", len(data), err)
+	}
+}
+
+func TestFailoverOpenTimeout(t *testing.T) {
+	primary, secondary := newFailoverServers(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+		next.ServeHTTP(w, r)
+	})
+	fsys := NewFailoverFS(primary, secondary, FailoverOptions{OpenTimeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	f, err := fsys.Open("passengers.txt")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	f.Close()
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("error: Open waited for the slow primary")
+	}
+}
+
+func TestFailoverRead(t *testing.T) {
+	primary, secondary := newFailoverServers(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		if r.Method == http.MethodGet {
+			w = &cutWriter{ResponseWriter: w, limit: 100 << 10}
+		}
+		next.ServeHTTP(w, r)
+	})
+	fsys := NewFailoverFS(primary, secondary, FailoverOptions{})
+
+	data, err := ReadFile(fsys, "test_dataset_large.json")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if !bytes.Equal(data, bytes.Repeat([]byte("s"), 300<<10)) {
+		t.Fatalf("error: unexpected data of %d bytes", len(data))
+	}
+}
+
+func TestFailoverNotExist(t *testing.T) {
+	primary, secondary := newFailoverServers(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	fsys := NewFailoverFS(primary, secondary, FailoverOptions{})
+
+	if _, err := fsys.Open("passengers.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist from the primary, got %v", err)
+	}
+}
+
+func TestFailoverOpenTimeoutNotExist(t *testing.T) {
+	primary, _ := newFailoverServers(t, func(w http.ResponseWriter, r *http.Request, next http.Handler) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	srv := newTestServer(t)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		slow.CloseClientConnections()
+		slow.Close()
+	})
+	secondary := New("public-sample-data", "us-east-1", WithEndpoint(slow.URL), WithAnonymousCredentials())
+	fsys := NewFailoverFS(primary, secondary, FailoverOptions{OpenTimeout: 10 * time.Millisecond})
+
+	// the secondary was started by OpenTimeout, but the object was deleted from the primary
+	if _, err := fsys.Open("passengers.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist from the primary, got %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path"
+	"strings"
+)
+
+// ContextFileSystem is an http.FileSystem whose files can be opened with a context,
+// like FileSystem and MultiFS
+type ContextFileSystem interface {
+	http.FileSystem
+	OpenContext(ctx context.Context, name string) (http.File, error)
+}
+
+type fileHandler struct {
+	fs          ContextFileSystem
+	headers     *HeaderPolicy
+	compressor  *compressor
+	sniff       bool
+	disposition DispositionFunc
+	names       NameOptions
+
+	// invalidator evicts the cached variants until invalidatorCtx is done, see WithInvalidator
+	invalidator    Invalidator
+	invalidatorCtx context.Context
+}
+
+// HandlerOption configures the handler of FileServer
+type HandlerOption func(*fileHandler)
+
+// FileServer returns a handler that serves the objects of fs with http.ServeContent.
+// File is seekable, so Content-Length, Accept-Ranges, range requests, conditional
+// requests (using the ETag and LastModified of the object) and HEAD requests are
+// handled like they are for local files. Request paths are normalized with the StrictNames
+// NameOptions, so suspicious paths get a 400 Bad Request; see WithNameOptions.
+func FileServer(fs ContextFileSystem, opts ...HandlerOption) http.Handler {
+	h := &fileHandler{fs: fs, names: StrictNames}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.invalidator != nil {
+		go h.runInvalidator(h.invalidatorCtx, h.invalidator)
+	}
+	return h
+}
+
+// DefaultForwardedHeaders are the object headers forwarded by a HeaderPolicy without Allow
+var DefaultForwardedHeaders = []string{"Cache-Control", "Content-Disposition", "Content-Language", "Expires"}
+
+// HeaderPolicy selects the headers stored with an object (see File.Header) that FileServer
+// forwards to clients. Allow and Deny contain header names, or prefixes ending in "*" such as
+// "X-Amz-Meta-*", matched case-insensitively; a header is forwarded if it matches Allow and
+// doesn't match Deny. Override headers are set on every response and replace forwarded ones.
+type HeaderPolicy struct {
+	// Allow defaults to DefaultForwardedHeaders
+	Allow    []string
+	Deny     []string
+	Override http.Header
+}
+
+// WithHeaderPolicy makes FileServer forward object headers such as Cache-Control according
+// to p. By default no object headers are forwarded.
+func WithHeaderPolicy(p HeaderPolicy) HandlerOption {
+	return func(h *fileHandler) {
+		if p.Allow == nil {
+			p.Allow = DefaultForwardedHeaders
+		}
+		h.headers = &p
+	}
+}
+
+// WithNameOptions replaces the StrictNames NameOptions that FileServer normalizes request paths
+// with. NameOptions{} only cleans paths and rejects those with ".." segments.
+func WithNameOptions(opts NameOptions) HandlerOption {
+	return func(h *fileHandler) {
+		h.names = opts
+	}
+}
+
+// WithContentSniffing makes FileServer guess the content type of objects stored without one or
+// as application/octet-stream or binary/octet-stream, from the extension of the name or else
+// from the first 512 bytes with http.DetectContentType.
+func WithContentSniffing() HandlerOption {
+	return func(h *fileHandler) {
+		h.sniff = true
+	}
+}
+
+// Disposition is the Content-Disposition of a response
+type Disposition struct {
+	// Attachment makes browsers download the file rather than display it inline
+	Attachment bool
+	// Filename is the name browsers save the file as. The name of the file is used if it is
+	// empty and Attachment is set.
+	Filename string
+}
+
+// DispositionFunc returns the Content-Disposition of the response to r for the file, or false
+// to leave it unset or as forwarded by the HeaderPolicy
+type DispositionFunc func(r *http.Request, stat os.FileInfo) (Disposition, bool)
+
+// WithContentDisposition makes FileServer set the Content-Disposition returned by fn for
+// every file it serves, for example to serve ?download=1 as an attachment
+func WithContentDisposition(fn DispositionFunc) HandlerOption {
+	return func(h *fileHandler) {
+		h.disposition = fn
+	}
+}
+
+// header returns the value of the Content-Disposition header
+func (d Disposition) header(name string) string {
+	kind := "inline"
+	if d.Attachment {
+		kind = "attachment"
+	}
+
+	filename := d.Filename
+	if filename == "" && d.Attachment {
+		filename = name
+	}
+	if filename == "" {
+		return kind
+	}
+
+	return mime.FormatMediaType(kind, map[string]string{"filename": filename})
+}
+
+// genericContentType reports whether contentType says nothing about the content
+func genericContentType(contentType string) bool {
+	switch contentType {
+	case "", "application/octet-stream", "binary/octet-stream":
+		return true
+	}
+	return false
+}
+
+// apply sets the headers of object allowed by the policy and the overrides on w
+func (p *HeaderPolicy) apply(w http.Header, object http.Header) {
+	for name, values := range object {
+		if matchHeader(p.Allow, name) && !matchHeader(p.Deny, name) {
+			w[name] = values
+		}
+	}
+
+	for name, values := range p.Override {
+		w[textproto.CanonicalMIMEHeaderKey(name)] = values
+	}
+}
+
+// matchHeader reports whether the canonical header name matches one of the patterns
+func matchHeader(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+				return true
+			}
+		} else if strings.EqualFold(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, err := NormalizeName(r.URL.Path, h.names)
+	if err != nil {
+		msg, code := toHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+
+	f, err := h.fs.OpenContext(r.Context(), name)
+	if err != nil {
+		msg, code := toHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		msg, code := toHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+
+	if stat.IsDir() {
+		msg, code := toHTTPError(os.ErrNotExist)
+		http.Error(w, msg, code)
+		return
+	}
+
+	if file, ok := f.(*File); ok {
+		// without a Content-Type, http.ServeContent and serveStream guess it
+		if file.contentType != "" && !(h.sniff && genericContentType(file.contentType)) {
+			w.Header().Set("Content-Type", file.contentType)
+		}
+		if file.etag != "" {
+			w.Header().Set("Etag", file.etag)
+		}
+		if encoding := file.header.Get("Content-Encoding"); encoding != "" {
+			// the object is stored compressed, and served like S3 serves it
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		if h.headers != nil {
+			h.headers.apply(w.Header(), file.header)
+		}
+	} else if h.headers != nil {
+		h.headers.apply(w.Header(), nil)
+	}
+
+	if h.disposition != nil {
+		if d, ok := h.disposition(r, stat); ok {
+			w.Header().Set("Content-Disposition", d.header(stat.Name()))
+		}
+	}
+
+	if stat.Size() < 0 {
+		serveStream(w, r, stat, f)
+		return
+	}
+
+	if file, ok := f.(*File); ok && h.compressor != nil && h.compressor.serve(w, r, file) {
+		return
+	}
+
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+}
+
+// serveStream serves a file of unknown size with chunked transfer encoding, since
+// http.ServeContent needs to seek to the end of the content to find its length
+func serveStream(w http.ResponseWriter, r *http.Request, stat os.FileInfo, f http.File) {
+	if w.Header().Get("Content-Type") == "" {
+		if ctype := mime.TypeByExtension(path.Ext(stat.Name())); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+	}
+	if etag := w.Header().Get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		// the ETag is the one of the stored bytes, not of the decompressed content
+		w.Header().Set("Etag", "W/"+etag)
+	}
+	if !stat.ModTime().IsZero() {
+		w.Header().Set("Last-Modified", stat.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, f)
+	}
+}
+
+// toHTTPError returns a non-specific HTTP error message and status code for err
+func toHTTPError(err error) (msg string, code int) {
+	if errors.Is(err, os.ErrNotExist) {
+		return "404 page not found", http.StatusNotFound
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return "403 Forbidden", http.StatusForbidden
+	}
+	if errors.Is(err, ErrInvalidName) {
+		return "400 Bad Request", http.StatusBadRequest
+	}
+	return "500 Internal Server Error", http.StatusInternalServerError
+}
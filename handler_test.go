@@ -0,0 +1,96 @@
+package s3fs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestHeaderPolicy(t *testing.T) {
+	object := http.Header{
+		"Cache-Control":       {"max-age=3600"},
+		"Content-Disposition": {"inline"},
+		"Content-Encoding":    {"gzip"},
+		"X-Amz-Meta-Owner":    {"docs"},
+		"X-Amz-Meta-Secret":   {"hunter2"},
+	}
+
+	var h fileHandler
+	WithHeaderPolicy(HeaderPolicy{
+		Allow:    []string{"cache-control", "x-amz-meta-*"},
+		Deny:     []string{"X-Amz-Meta-Secret"},
+		Override: http.Header{"cache-control": {"no-store"}, "X-Frame-Options": {"DENY"}},
+	})(&h)
+
+	w := http.Header{}
+	h.headers.apply(w, object)
+
+	want := http.Header{
+		"Cache-Control":    {"no-store"},
+		"X-Amz-Meta-Owner": {"docs"},
+		"X-Frame-Options":  {"DENY"},
+	}
+	if !reflect.DeepEqual(w, want) {
+		t.Fatalf("error: expected %v, got %v", want, w)
+	}
+
+	WithHeaderPolicy(HeaderPolicy{})(&h)
+	w = http.Header{}
+	h.headers.apply(w, object)
+
+	want = http.Header{
+		"Cache-Control":       {"max-age=3600"},
+		"Content-Disposition": {"inline"},
+	}
+	if !reflect.DeepEqual(w, want) {
+		t.Fatalf("error: expected default headers %v, got %v", want, w)
+	}
+}
+
+func TestContentSniffing(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PutObject("public-sample-data", "page", []byte("<!DOCTYPE html><h1>Home</h1>"), "")
+	s3Fs := newTestFileSystem(srv)
+
+	cases := map[bool]string{
+		false: "binary/octet-stream",
+		true:  "text/html; charset=utf-8",
+	}
+	for sniff, want := range cases {
+		var opts []HandlerOption
+		if sniff {
+			opts = append(opts, WithContentSniffing())
+		}
+
+		w := httptest.NewRecorder()
+		FileServer(s3Fs, opts...).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page", nil))
+		if got := w.Header().Get("Content-Type"); got != want {
+			t.Fatalf("error: expected Content-Type %s with sniffing %v, got %s", want, sniff, got)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t))
+	handler := FileServer(s3Fs, WithContentDisposition(func(r *http.Request, stat os.FileInfo) (Disposition, bool) {
+		if r.URL.Query().Get("download") == "" {
+			return Disposition{}, false
+		}
+		return Disposition{Attachment: true, Filename: r.URL.Query().Get("as")}, true
+	}))
+
+	cases := map[string]string{
+		"/passengers.txt":                     "",
+		"/passengers.txt?download=1":          `attachment; filename=passengers.txt`,
+		"/passengers.txt?download=1&as=ü.txt": `attachment; filename*=utf-8''%C3%BC.txt`,
+	}
+	for target, want := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if got := w.Header().Get("Content-Disposition"); got != want {
+			t.Fatalf("error: expected Content-Disposition %q for %s, got %q", want, target, got)
+		}
+	}
+}
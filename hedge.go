@@ -0,0 +1,109 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// WithHedging makes every GetObject that hasn't received the headers of its response after
+// delay send the same request a second time and use whichever response comes first, canceling
+// the other. It trades a few more requests for a lower tail latency when serving small objects:
+// a delay around the 95th percentile latency of GetObject hedges about 5% of them.
+func WithHedging(delay time.Duration) Option {
+	return func(f *FileSystem) {
+		f.hedgeDelay = delay
+	}
+}
+
+// hedgeResult is the response of one of the requests of a hedged GetObject
+type hedgeResult struct {
+	object  *s3.GetObjectOutput
+	err     error
+	attempt int
+}
+
+// sendGetObject sends the GetObject, hedged with WithHedging
+func (f FileSystem) sendGetObject(ctx context.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	if f.hedgeDelay <= 0 {
+		return f.s3.GetObjectWithContext(ctx, input, opts...)
+	}
+
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(ctx)
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+
+		in := *input
+		go func() {
+			object, err := f.s3.GetObjectWithContext(ctx, &in, opts...)
+			results <- hedgeResult{object: object, err: err, attempt: attempt}
+		}()
+	}
+
+	send()
+	pending := 1
+
+	timer := time.NewTimer(f.hedgeDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			f.logger.Log(ctx, slog.LevelDebug, "s3fs: hedging request", "key", aws.StringValue(input.Key),
+				"range", aws.StringValue(input.Range))
+			pending++
+			send()
+		case r := <-results:
+			pending--
+			if r.err != nil && pending > 0 {
+				// the other request may still succeed
+				cancels[r.attempt]()
+				continue
+			}
+
+			for attempt, cancel := range cancels {
+				if attempt != r.attempt {
+					cancel()
+				}
+			}
+			if pending > 0 {
+				go discardHedge(results)
+			}
+
+			if r.err != nil {
+				cancels[r.attempt]()
+				return nil, r.err
+			}
+			r.object.Body = cancelingBody{ReadCloser: r.object.Body, cancel: cancels[r.attempt]}
+			return r.object, nil
+		}
+	}
+}
+
+// discardHedge closes the body of the request that lost, in case it succeeded before it
+// was canceled
+func discardHedge(results <-chan hedgeResult) {
+	if r := <-results; r.err == nil {
+		r.object.Body.Close()
+	}
+}
+
+// cancelingBody cancels the context of its request when it is closed
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,42 @@
+package s3fs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedging(t *testing.T) {
+	srv := newTestServer(t)
+
+	// the first GetObject is slow, the ones after it are fast
+	var gets atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && gets.Add(1) == 1 {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	s3Fs := New("public-sample-data", "us-east-1", WithEndpoint(slow.URL), WithAnonymousCredentials(),
+		WithHedging(50*time.Millisecond))
+
+	start := time.Now()
+	data, err := ReadFile(s3Fs, "passengers.txt")
+	if err != nil || len(data) != 1046 {
+		t.Fatalf("error: read %d bytes, %v", len(data), err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("error: the request wasn't hedged")
+	}
+	if n := gets.Load(); n != 2 {
+		t.Fatalf("error: expected 2 GetObjects, got %d", n)
+	}
+}
@@ -0,0 +1,56 @@
+package s3fs
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Request is a request about to be sent to S3, passed to a RequestInterceptor
+type Request struct {
+	// Operation is "GetObject", "HeadObject", "ListObjectsV2" or "ListObjectVersions"
+	Operation string
+	// Input is the *s3.GetObjectInput, *s3.HeadObjectInput, *s3.ListObjectsV2Input or
+	// *s3.ListObjectVersionsInput of the request, which can be changed, for example to set
+	// ExpectedBucketOwner or the Range
+	Input interface{}
+	// Header holds headers added to the HTTP request, for headers the input has no field for
+	Header http.Header
+}
+
+// RequestInterceptor is called with every GetObject, HeadObject, ListObjectsV2 and
+// ListObjectVersions request before it is sent, with the context of the call, so it can carry values such as tracing
+// baggage into headers. Returning an error fails the call with it without sending the request.
+// It is called once per call; the headers it adds are also sent by retries and WithHedging.
+type RequestInterceptor func(ctx context.Context, r *Request) error
+
+// WithRequestInterceptor sets the RequestInterceptor called before GetObject, HeadObject,
+// ListObjectsV2 and ListObjectVersions requests
+func WithRequestInterceptor(fn RequestInterceptor) Option {
+	return func(f *FileSystem) {
+		f.interceptor = fn
+	}
+}
+
+// intercept calls the RequestInterceptor with the input of the operation and returns the
+// request.Option adding the headers it set
+func (f FileSystem) intercept(ctx context.Context, op string, input interface{}) ([]request.Option, error) {
+	if f.interceptor == nil {
+		return nil, nil
+	}
+
+	r := &Request{Operation: op, Input: input, Header: http.Header{}}
+	if err := f.interceptor(ctx, r); err != nil {
+		return nil, err
+	}
+	if len(r.Header) == 0 {
+		return nil, nil
+	}
+
+	return []request.Option{func(req *request.Request) {
+		for name, values := range r.Header {
+			req.HTTPRequest.Header[name] = values
+		}
+	}}, nil
+}
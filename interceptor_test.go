@@ -0,0 +1,109 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestRequestInterceptor(t *testing.T) {
+	srv := newTestServer(t)
+
+	var mu sync.Mutex
+	owners := map[string]string{}
+	baggage := map[string]string{}
+	recording := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		owners[r.Method] = r.Header.Get("X-Amz-Expected-Bucket-Owner")
+		baggage[r.Method] = r.Header.Get("Baggage")
+		mu.Unlock()
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer recording.Close()
+
+	var ops []string
+	s3Fs := New("public-sample-data", "us-east-1", WithEndpoint(recording.URL), WithAnonymousCredentials(),
+		WithRequestInterceptor(func(ctx context.Context, r *Request) error {
+			ops = append(ops, r.Operation)
+			switch input := r.Input.(type) {
+			case *s3.GetObjectInput:
+				input.ExpectedBucketOwner = aws.String("111122223333")
+				input.Range = aws.String("bytes=0-9")
+			case *s3.HeadObjectInput:
+				input.ExpectedBucketOwner = aws.String("111122223333")
+			case *s3.ListObjectsV2Input:
+				input.ExpectedBucketOwner = aws.String("111122223333")
+			}
+			r.Header.Set("Baggage", "tenant=acme")
+			return nil
+		}))
+
+	f, err := s3Fs.Open("passengers.txt")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if stat, _ := f.Stat(); stat.Size() != 10 {
+		t.Fatalf("error: expected the range of the interceptor, got %d bytes", stat.Size())
+	}
+	f.Close()
+
+	if _, err := s3Fs.Stat("passengers.txt"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, err := s3Fs.List(context.Background(), ListOptions{}).Next(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	if len(ops) != 3 || ops[0] != "GetObject" || ops[1] != "HeadObject" || ops[2] != "ListObjectsV2" {
+		t.Fatalf("error: intercepted %v", ops)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		if owners[method] != "111122223333" || baggage[method] != "tenant=acme" {
+			t.Fatalf("error: %s sent owner %q, baggage %q", method, owners[method], baggage[method])
+		}
+	}
+}
+
+func TestRequestInterceptorError(t *testing.T) {
+	denied := errors.New("denied")
+	s3Fs := newTestFileSystem(newTestServer(t), WithRequestInterceptor(func(ctx context.Context, r *Request) error {
+		return denied
+	}))
+
+	if _, err := s3Fs.Open("passengers.txt"); !errors.Is(err, denied) {
+		t.Fatalf("error: expected the error of the interceptor, got %v", err)
+	}
+}
+
+func TestRequestInterceptorListObjectVersions(t *testing.T) {
+	srv := newTestServer(t)
+	srv.EnableVersioning("public-sample-data")
+	denied := errors.New("denied")
+
+	var prefixes []string
+	s3Fs := newTestFileSystem(srv, WithDeleteMarkers(), WithRequestInterceptor(func(ctx context.Context, r *Request) error {
+		input, ok := r.Input.(*s3.ListObjectVersionsInput)
+		if !ok || r.Operation != "ListObjectVersions" {
+			return nil
+		}
+		prefixes = append(prefixes, aws.StringValue(input.Prefix))
+		if aws.StringValue(input.Prefix) == "secret.txt" {
+			return denied
+		}
+		return nil
+	}))
+
+	if _, err := s3Fs.Open("missing.txt"); !errors.Is(err, os.ErrNotExist) || len(prefixes) != 1 || prefixes[0] != "missing.txt" {
+		t.Fatalf("error: expected the ListObjectVersions of Open to be intercepted, got %v, %v", prefixes, err)
+	}
+	if err := s3Fs.Undelete("secret.txt"); !errors.Is(err, denied) {
+		t.Fatalf("error: expected the error of the interceptor, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package s3fs
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// Invalidator tells a cache of objects which keys to evict. Run calls evict with the key of
+// every object overwritten or deleted until ctx is done or a permanent error occurs.
+type Invalidator interface {
+	Run(ctx context.Context, evict func(key string)) error
+}
+
+// SQSInvalidator is an Invalidator consuming the S3 event notifications of a bucket from an
+// SQS queue, either sent directly by S3 or through an SNS topic. Messages are deleted from the
+// queue once their keys are evicted, so the queue shouldn't be shared by several consumers.
+type SQSInvalidator struct {
+	client   sqsiface.SQSAPI
+	queueURL string
+	bucket   string
+}
+
+// NewSQSInvalidator creates an SQSInvalidator receiving the notifications of bucket from the
+// queue at queueURL. Notifications of other buckets are deleted without evicting anything.
+func NewSQSInvalidator(client sqsiface.SQSAPI, queueURL, bucket string) *SQSInvalidator {
+	return &SQSInvalidator{client: client, queueURL: queueURL, bucket: bucket}
+}
+
+// Run long polls the queue and evicts the keys of ObjectCreated and ObjectRemoved events.
+// It returns ctx.Err() when ctx is done, or the error of a failed ReceiveMessage.
+func (s *SQSInvalidator) Run(ctx context.Context, evict func(key string)) error {
+	for {
+		out, err := s.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.queueURL),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(20),
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, message := range out.Messages {
+			// messages that aren't S3 notifications are dropped rather than
+			// received again until they reach the dead-letter queue
+			keys, _ := changedKeys(aws.StringValue(message.Body), s.bucket)
+			for _, key := range keys {
+				evict(key)
+			}
+
+			_, err := s.client.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(s.queueURL),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchInvalidator returns an Invalidator polling the keys starting with prefix every
+// interval with Watch, for buckets without event notifications
+func (f FileSystem) WatchInvalidator(prefix string, interval time.Duration) Invalidator {
+	return watchInvalidator{fs: f, prefix: prefix, interval: interval}
+}
+
+type watchInvalidator struct {
+	fs       FileSystem
+	prefix   string
+	interval time.Duration
+}
+
+func (w watchInvalidator) Run(ctx context.Context, evict func(key string)) error {
+	for event := range w.fs.Watch(ctx, w.prefix, w.interval) {
+		if event.Err == nil {
+			evict(event.Key)
+		}
+	}
+
+	return ctx.Err()
+}
+
+// WithInvalidator makes FileServer run inv until ctx is done and, for every key it reports,
+// evict the variants cached by WithCompression and, when the file system is a FileSystem, the
+// write staged by WithWriteStaging. A staged write is then only kept until its own notification
+// arrives. If Run fails, compressed variants are no longer cached, as they can't be evicted.
+func WithInvalidator(ctx context.Context, inv Invalidator) HandlerOption {
+	return func(h *fileHandler) {
+		h.invalidator = inv
+		h.invalidatorCtx = ctx
+	}
+}
+
+// runInvalidator runs inv, evicting the keys it reports from the caches of the handler
+func (h *fileHandler) runInvalidator(ctx context.Context, inv Invalidator) {
+	staged, _ := h.fs.(interface{ evictStaged(key string) })
+
+	err := inv.Run(ctx, func(key string) {
+		if h.compressor != nil {
+			h.compressor.cache.evict(key)
+		}
+		if staged != nil {
+			staged.evictStaged(key)
+		}
+	})
+	if err != nil && ctx.Err() == nil && h.compressor != nil {
+		h.compressor.cache.disable()
+	}
+}
+
+// s3Event is the part of an S3 event notification needed to find the changed keys
+type s3Event struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	}
+}
+
+// snsNotification is an SNS message delivered to SQS without raw message delivery
+type snsNotification struct {
+	Type    string
+	Message string
+}
+
+// changedKeys returns the keys of the objects of bucket created or removed by a notification
+func changedKeys(body, bucket string) ([]string, error) {
+	var notification snsNotification
+	if err := json.Unmarshal([]byte(body), &notification); err == nil && notification.Type == "Notification" {
+		body = notification.Message
+	}
+
+	var event s3Event
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, record := range event.Records {
+		if record.S3.Bucket.Name != bucket {
+			continue
+		}
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") && !strings.HasPrefix(record.EventName, "ObjectRemoved:") {
+			continue
+		}
+
+		// keys are URL-encoded in notifications, with spaces as "+"
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
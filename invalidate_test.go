@@ -0,0 +1,159 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+func TestChangedKeys(t *testing.T) {
+	event := `{"Records":[
+		{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"site"},"object":{"key":"docs/setup+guide.html"}}},
+		{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"site"},"object":{"key":"caf%C3%A9.png"}}},
+		{"eventName":"ObjectRestore:Completed","s3":{"bucket":{"name":"site"},"object":{"key":"archive.tar"}}},
+		{"eventName":"ObjectCreated:Copy","s3":{"bucket":{"name":"other"},"object":{"key":"index.html"}}}
+	]}`
+	want := []string{"docs/setup guide.html", "café.png"}
+
+	keys, err := changedKeys(event, "site")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("error: expected %v, got %v", want, keys)
+	}
+
+	sns := `{"Type":"Notification","Message":` + strconv.Quote(event) + `}`
+	keys, err = changedKeys(sns, "site")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("error: expected %v from SNS notification, got %v", want, keys)
+	}
+
+	keys, err = changedKeys(`{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"site"}`, "site")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("error: expected no keys for test event, got %v, %v", keys, err)
+	}
+}
+
+// fakeQueue is an SQS queue delivering the bodies of messages, one per ReceiveMessage
+type fakeQueue struct {
+	sqsiface.SQSAPI
+	messages chan string
+	deleted  chan string
+}
+
+func (q *fakeQueue) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	select {
+	case body := <-q.messages:
+		return &sqs.ReceiveMessageOutput{Messages: []*sqs.Message{{Body: aws.String(body), ReceiptHandle: aws.String(body)}}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *fakeQueue) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	q.deleted <- aws.StringValue(input.ReceiptHandle)
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// send delivers an ObjectCreated notification of the key and waits until it is handled
+func (q *fakeQueue) send(t *testing.T, key string) {
+	q.messages <- `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"public-sample-data"},"object":{"key":"` + key + `"}}}]}`
+	select {
+	case <-q.deleted:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("error: the notification of %s wasn't handled", key)
+	}
+}
+
+func TestWithInvalidator(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv, WithWriteStaging(StagingOptions{}))
+	srv.PutObject("public-sample-data", "index.html", []byte(strings.Repeat("<p>hello</p>", 200)), "text/html")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := &fakeQueue{messages: make(chan string), deleted: make(chan string)}
+	h := FileServer(s3Fs, WithCompression(CompressionOptions{}),
+		WithInvalidator(ctx, NewSQSInvalidator(queue, "queue", "public-sample-data"))).(*fileHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if cachedVariants(h) != 1 {
+		t.Fatalf("error: expected the compressed variant to be cached")
+	}
+
+	queue.send(t, "index.html")
+	if cachedVariants(h) != 0 {
+		t.Fatalf("error: expected the notification to evict the compressed variant")
+	}
+
+	if err := s3Fs.WriteFile("passengers.txt", []byte("staged"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	srv.PutObject("public-sample-data", "passengers.txt", []byte("overwritten"), "text/plain")
+	queue.send(t, "passengers.txt")
+	if data, err := ReadFile(s3Fs, "passengers.txt"); err != nil || string(data) != "overwritten" {
+		t.Fatalf("error: expected the notification to evict the staged write, read %q, %v", data, err)
+	}
+}
+
+// failingInvalidator is an Invalidator whose Run fails at once
+type failingInvalidator struct{ done chan struct{} }
+
+func (i failingInvalidator) Run(ctx context.Context, evict func(key string)) error {
+	defer close(i.done)
+	return errors.New("access denied")
+}
+
+func TestWithInvalidatorFailure(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PutObject("public-sample-data", "index.html", []byte(strings.Repeat("<p>hello</p>", 200)), "text/html")
+
+	inv := failingInvalidator{done: make(chan struct{})}
+	h := FileServer(newTestFileSystem(srv), WithCompression(CompressionOptions{}),
+		WithInvalidator(context.Background(), inv)).(*fileHandler)
+	<-inv.done
+	// the cache is disabled right after Run returns
+	for deadline := time.Now().Add(5 * time.Second); cacheSize(h) != 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("error: expected the file to still be compressed")
+	}
+	if cachedVariants(h) != 0 {
+		t.Fatalf("error: expected no variants to be cached once the invalidator failed")
+	}
+}
+
+func cachedVariants(h *fileHandler) int {
+	h.compressor.cache.mu.Lock()
+	defer h.compressor.cache.mu.Unlock()
+	return len(h.compressor.cache.entries)
+}
+
+func cacheSize(h *fileHandler) int64 {
+	h.compressor.cache.mu.Lock()
+	defer h.compressor.cache.mu.Unlock()
+	return h.compressor.cache.maxSize
+}
@@ -0,0 +1,37 @@
+package s3fs
+
+import (
+	"path"
+	"strings"
+)
+
+// KeyMapper maps the path passed to Open, usually the path of a request URL, to the key of an
+// object. It allows clean URLs to be served from hashed or dated keys, for example
+// /img/logo.png from assets/2024/logo-abc123.png. Returning os.ErrNotExist makes Open fail
+// like it does for a missing object.
+type KeyMapper func(urlPath string) (key string, err error)
+
+// BaseKeyMapper uses the base name of the path as the key. It is the default KeyMapper.
+func BaseKeyMapper(urlPath string) (string, error) {
+	return path.Base(urlPath), nil
+}
+
+// PathKeyMapper uses the cleaned path without its leading slash as the key, so /img/logo.png
+// is served from img/logo.png
+func PathKeyMapper(urlPath string) (string, error) {
+	return strings.TrimPrefix(path.Clean("/"+urlPath), "/"), nil
+}
+
+// key returns the key of the object for name, normalized with WithNameNormalization
+func (f FileSystem) key(name string) (string, error) {
+	name, err := f.normalizeName(name)
+	if err != nil {
+		return "", err
+	}
+
+	if f.keyMapper == nil {
+		return BaseKeyMapper(name)
+	}
+
+	return f.keyMapper(name)
+}
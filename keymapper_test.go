@@ -0,0 +1,50 @@
+package s3fs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPathKeyMapper(t *testing.T) {
+	cases := map[string]string{
+		"/img/logo.png":     "img/logo.png",
+		"img/logo.png":      "img/logo.png",
+		"/img/../logo.png":  "logo.png",
+		"//img//logo.png":   "img/logo.png",
+		"/../../secret.txt": "secret.txt",
+		"/passengers.txt":   "passengers.txt",
+	}
+
+	for urlPath, want := range cases {
+		key, err := PathKeyMapper(urlPath)
+		if err != nil {
+			t.Fatalf("error: mapping %s: %s", urlPath, err)
+		}
+		if key != want {
+			t.Fatalf("error: %s mapped to %s, want %s", urlPath, key, want)
+		}
+	}
+}
+
+func TestKeyMapper(t *testing.T) {
+	s3Fs := New("public-sample-data", "us-east-1")
+	if key, _ := s3Fs.key("/data/passengers.txt"); key != "passengers.txt" {
+		t.Fatalf("error: default key mapper returned %s", key)
+	}
+
+	assets := map[string]string{"/img/logo.png": "assets/2024/logo-abc123.png"}
+	s3Fs = New("public-sample-data", "us-east-1", WithKeyMapper(func(urlPath string) (string, error) {
+		if key, ok := assets[urlPath]; ok {
+			return key, nil
+		}
+		return "", os.ErrNotExist
+	}))
+
+	if key, _ := s3Fs.key("/img/logo.png"); key != "assets/2024/logo-abc123.png" {
+		t.Fatalf("error: key mapper returned %s", key)
+	}
+
+	if _, err := s3Fs.Open("/img/missing.png"); err != os.ErrNotExist {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+}
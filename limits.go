@@ -0,0 +1,166 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// WithRateLimit limits the rate at which the bodies of objects are read to bytesPerSecond,
+// shared by every File of the FileSystem, so that a server doesn't saturate its egress.
+// A bytesPerSecond of 0 or less means no limit.
+func WithRateLimit(bytesPerSecond int64) Option {
+	return func(f *FileSystem) {
+		if bytesPerSecond <= 0 {
+			f.rateLimit = nil
+			return
+		}
+		f.rateLimit = newTokenBucket(bytesPerSecond)
+	}
+}
+
+// WithMaxConcurrentRequests limits the number of S3 requests of the FileSystem in flight at
+// the same time to n. Requests over the limit wait for a slot or for their context to be done.
+// A GetObject keeps its slot until the body of the object is closed. An n of 0 or less means
+// no limit.
+func WithMaxConcurrentRequests(n int) Option {
+	return func(f *FileSystem) {
+		if n <= 0 {
+			f.requestSlots = nil
+			return
+		}
+		f.requestSlots = make(chan struct{}, n)
+	}
+}
+
+// installLimits adds the handlers enforcing the limits of the options to the S3 client
+func (f FileSystem) installLimits() {
+	if f.requestSlots != nil {
+		f.s3.Handlers.Build.PushFront(f.acquireSlot)
+	}
+
+	if f.rateLimit != nil {
+		f.s3.Handlers.Complete.PushBack(func(r *request.Request) {
+			if object, ok := r.Data.(*s3.GetObjectOutput); ok && r.Error == nil && object.Body != nil {
+				object.Body = &throttledBody{ReadCloser: object.Body, ctx: r.Context(), bucket: f.rateLimit}
+			}
+		})
+	}
+}
+
+// acquireSlot waits for a request slot and releases it when the request completes, or
+// when the body of a GetObject is closed. Build handlers only run once per request, while
+// the handlers of later phases run again for every retry.
+func (f FileSystem) acquireSlot(r *request.Request) {
+	select {
+	case f.requestSlots <- struct{}{}:
+	case <-r.Context().Done():
+		r.Error = r.Context().Err()
+		return
+	}
+
+	r.Handlers.Complete.PushBack(func(r *request.Request) {
+		if object, ok := r.Data.(*s3.GetObjectOutput); ok && r.Error == nil && object.Body != nil {
+			object.Body = &releasingBody{ReadCloser: object.Body, release: f.releaseSlot}
+			return
+		}
+		f.releaseSlot()
+	})
+}
+
+func (f FileSystem) releaseSlot() {
+	<-f.requestSlots
+}
+
+// releasingBody calls release once when it is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// throttledBody waits for tokens of the bucket for every byte read
+type throttledBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	bucket *tokenBucket
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if burst := b.bucket.burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := b.bucket.wait(b.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err
+}
+
+// tokenBucket is a token bucket refilled at rate tokens per second holding at most a
+// second worth of tokens
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// burst returns the largest number of tokens the bucket can hold
+func (b *tokenBucket) burst() int {
+	if b.rate < 1 {
+		return 1
+	}
+	return int(b.rate)
+}
+
+// wait takes n tokens, waiting until they have been refilled if the bucket doesn't hold
+// enough. Tokens are taken even if ctx is done before the wait is over.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
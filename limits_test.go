@@ -0,0 +1,77 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(1000)
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := bucket.wait(ctx, 1000); err != nil {
+		t.Fatalf("error: waiting for the initial burst: %s", err)
+	}
+	if err := bucket.wait(ctx, 200); err != nil {
+		t.Fatalf("error: waiting for tokens: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("error: 1200 bytes at 1000 bytes/s took only %s", elapsed)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := bucket.wait(canceled, 1000); err != context.Canceled {
+		t.Fatalf("error: expected context.Canceled, got %v", err)
+	}
+}
+
+func TestThrottledBody(t *testing.T) {
+	body := &throttledBody{
+		ReadCloser: io.NopCloser(strings.NewReader(strings.Repeat("x", 300))),
+		ctx:        context.Background(),
+		bucket:     newTokenBucket(100),
+	}
+
+	p := make([]byte, 300)
+	n, _ := body.Read(p)
+	if n != 100 {
+		t.Fatalf("error: read %d bytes, expected reads to be limited to the burst of 100", n)
+	}
+}
+
+func TestReleasingBody(t *testing.T) {
+	released := 0
+	body := &releasingBody{ReadCloser: io.NopCloser(strings.NewReader("")), release: func() { released++ }}
+	body.Close()
+	body.Close()
+
+	if released != 1 {
+		t.Fatalf("error: released %d times, want 1", released)
+	}
+}
+
+func TestNoLimits(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t), WithMaxConcurrentRequests(0), WithRateLimit(-1))
+	if s3Fs.requestSlots != nil || s3Fs.rateLimit != nil {
+		t.Fatalf("error: expected non-positive limits to mean no limit")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ReadFile(s3Fs, "passengers.txt")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("error: reading without limits blocked")
+	}
+}
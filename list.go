@@ -0,0 +1,191 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ObjectInfo is an object, or a common prefix of a listing with a delimiter, returned by
+// ListIterator.Next
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	StorageClass string
+	// IsPrefix is set for the common prefixes of a listing with a delimiter. Only Key,
+	// which ends in the delimiter, is set for them.
+	IsPrefix bool
+	// VersionID is the version ID of the latest version, for listings with DeleteMarkers
+	VersionID string
+	// Deleted is set for keys whose latest version is a delete marker, listed with
+	// DeleteMarkers. Only Key, VersionID, the ID of the marker, and LastModified are set.
+	Deleted bool
+}
+
+// ListOptions configures List
+type ListOptions struct {
+	// Prefix limits the listing to keys starting with it
+	Prefix string
+	// Delimiter groups the keys containing it after the prefix into common prefixes
+	Delimiter string
+	// StartAfter starts the listing after the key
+	StartAfter string
+	// PageSize is the number of keys fetched with every ListObjectsV2, 1000 if it is zero
+	PageSize int64
+	// DeleteMarkers lists the latest version of every key with ListObjectVersions instead,
+	// including the deleted keys of versioned buckets, so they can be found and restored
+	// with Undelete. Pages hold up to PageSize versions, of which only the latest are
+	// returned, so listings of keys with many versions fetch more pages.
+	DeleteMarkers bool
+}
+
+// ListIterator iterates over the keys of a bucket. See FileSystem.List
+type ListIterator struct {
+	fs    FileSystem
+	ctx   context.Context
+	input *s3.ListObjectsV2Input
+	// versions is the input of listings with DeleteMarkers
+	versions *s3.ListObjectVersionsInput
+	page     []ObjectInfo
+	done     bool
+	err      error
+}
+
+// List returns a ListIterator over the keys of the bucket with opts, in the lexicographic
+// order of ListObjectsV2. Keys are fetched one page at a time as Next is called, so only a
+// single page is ever held in memory. Keys are those of the bucket, not names mapped with
+// the KeyMapper.
+func (f FileSystem) List(ctx context.Context, opts ListOptions) *ListIterator {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(f.bucket),
+	}
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	if opts.StartAfter != "" {
+		input.StartAfter = aws.String(opts.StartAfter)
+	}
+	if opts.PageSize > 0 {
+		input.MaxKeys = aws.Int64(opts.PageSize)
+	}
+
+	it := &ListIterator{fs: f, ctx: ctx, input: input}
+	if opts.DeleteMarkers {
+		it.versions = &s3.ListObjectVersionsInput{
+			Bucket:    input.Bucket,
+			Prefix:    input.Prefix,
+			Delimiter: input.Delimiter,
+			KeyMarker: input.StartAfter,
+			MaxKeys:   input.MaxKeys,
+		}
+	}
+	return it
+}
+
+// Next returns the next object of the listing. It returns io.EOF after the last object and
+// keeps returning the error of a failed ListObjectsV2.
+func (it *ListIterator) Next() (ObjectInfo, error) {
+	for len(it.page) == 0 {
+		if it.err != nil {
+			return ObjectInfo{}, it.err
+		}
+		if it.done {
+			return ObjectInfo{}, io.EOF
+		}
+		if it.versions != nil {
+			it.fetchVersions()
+		} else {
+			it.fetch()
+		}
+	}
+
+	object := it.page[0]
+	it.page = it.page[1:]
+	return object, nil
+}
+
+// fetch lists the next page, merging the objects and common prefixes in key order
+func (it *ListIterator) fetch() {
+	list, err := it.fs.listObjects(it.ctx, it.input)
+	if err != nil {
+		it.err = toFSError(err)
+		return
+	}
+
+	page := make([]ObjectInfo, 0, len(list.Contents)+len(list.CommonPrefixes))
+	prefixes := list.CommonPrefixes
+	for _, object := range list.Contents {
+		key := aws.StringValue(object.Key)
+		for len(prefixes) > 0 && aws.StringValue(prefixes[0].Prefix) < key {
+			page = append(page, ObjectInfo{Key: aws.StringValue(prefixes[0].Prefix), IsPrefix: true})
+			prefixes = prefixes[1:]
+		}
+
+		page = append(page, ObjectInfo{
+			Key:          key,
+			Size:         aws.Int64Value(object.Size),
+			ETag:         aws.StringValue(object.ETag),
+			LastModified: aws.TimeValue(object.LastModified),
+			StorageClass: aws.StringValue(object.StorageClass),
+		})
+	}
+	for _, prefix := range prefixes {
+		page = append(page, ObjectInfo{Key: aws.StringValue(prefix.Prefix), IsPrefix: true})
+	}
+	it.page = page
+
+	it.input.ContinuationToken = list.NextContinuationToken
+	it.done = !aws.BoolValue(list.IsTruncated)
+}
+
+// fetchVersions lists the next page of versions, keeping the latest version of every key
+func (it *ListIterator) fetchVersions() {
+	list, err := it.fs.listObjectVersions(it.ctx, it.versions)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	page := make([]ObjectInfo, 0, len(list.Versions)+len(list.DeleteMarkers)+len(list.CommonPrefixes))
+	for _, v := range list.Versions {
+		if aws.BoolValue(v.IsLatest) {
+			page = append(page, ObjectInfo{
+				Key:          aws.StringValue(v.Key),
+				Size:         aws.Int64Value(v.Size),
+				ETag:         aws.StringValue(v.ETag),
+				LastModified: aws.TimeValue(v.LastModified),
+				StorageClass: aws.StringValue(v.StorageClass),
+				VersionID:    aws.StringValue(v.VersionId),
+			})
+		}
+	}
+	for _, marker := range list.DeleteMarkers {
+		if aws.BoolValue(marker.IsLatest) {
+			page = append(page, ObjectInfo{
+				Key:          aws.StringValue(marker.Key),
+				LastModified: aws.TimeValue(marker.LastModified),
+				VersionID:    aws.StringValue(marker.VersionId),
+				Deleted:      true,
+			})
+		}
+	}
+	for _, prefix := range list.CommonPrefixes {
+		page = append(page, ObjectInfo{Key: aws.StringValue(prefix.Prefix), IsPrefix: true})
+	}
+	// versions and delete markers are returned in separate lists
+	sort.Slice(page, func(i, j int) bool { return page[i].Key < page[j].Key })
+	it.page = page
+
+	it.versions.KeyMarker = list.NextKeyMarker
+	it.versions.VersionIdMarker = list.NextVersionIdMarker
+	it.done = !aws.BoolValue(list.IsTruncated)
+}
@@ -0,0 +1,59 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/shijuleon/s3fs/s3test"
+)
+
+func listKeys(t *testing.T, it *ListIterator) []string {
+	keys := []string{}
+	for {
+		object, err := it.Next()
+		if err == io.EOF {
+			return keys
+		}
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		keys = append(keys, object.Key)
+	}
+}
+
+func TestList(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+	srv.CreateBucket("public-sample-data")
+	for i := 0; i < 25; i++ {
+		srv.PutObject("public-sample-data", fmt.Sprintf("logs/%02d.log", i), []byte("log"), "")
+	}
+	srv.PutObject("public-sample-data", "logs/archive/2023.log", []byte("log"), "")
+	srv.PutObject("public-sample-data", "readme.txt", []byte("readme"), "")
+	s3Fs := newTestFileSystem(srv)
+
+	keys := listKeys(t, s3Fs.List(context.Background(), ListOptions{Prefix: "logs/", PageSize: 10}))
+	if len(keys) != 26 || keys[0] != "logs/00.log" || keys[25] != "logs/archive/2023.log" {
+		t.Fatalf("error: unexpected keys %v", keys)
+	}
+
+	keys = listKeys(t, s3Fs.List(context.Background(), ListOptions{Prefix: "logs/", Delimiter: "/", StartAfter: "logs/22.log", PageSize: 2}))
+	if fmt.Sprint(keys) != "[logs/23.log logs/24.log logs/archive/]" {
+		t.Fatalf("error: unexpected keys %v", keys)
+	}
+
+	it := s3Fs.List(context.Background(), ListOptions{Delimiter: "/"})
+	object, err := it.Next()
+	if err != nil || object.Key != "logs/" || !object.IsPrefix {
+		t.Fatalf("error: unexpected first object %+v, %v", object, err)
+	}
+	object, err = it.Next()
+	if err != nil || object.Key != "readme.txt" || object.Size != 6 {
+		t.Fatalf("error: unexpected second object %+v, %v", object, err)
+	}
+	if _, err := it.Next(); err != io.EOF {
+		t.Fatalf("error: expected io.EOF, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Logger receives the events of a FileSystem: opens, S3 requests and their retries, closed
+// bodies, lookups in the cache of compressed variants and errors, with the key, range, duration and bytes read as attributes. args are
+// alternating keys and values like in log/slog, and a *slog.Logger satisfies Logger.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+// WithLogger sets the Logger receiving the events of the FileSystem. Successful requests are
+// logged at slog.LevelDebug, retries at slog.LevelWarn and failed requests at slog.LevelError,
+// except for missing objects which are logged at slog.LevelDebug.
+func WithLogger(l Logger) Option {
+	return func(f *FileSystem) {
+		f.logger = l
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(context.Context, slog.Level, string, ...any) {}
+
+// requestDone reports a finished S3 request to the Metrics and the Logger of the FileSystem.
+// args are appended to the attributes of the log event.
+func (f FileSystem) requestDone(ctx context.Context, op string, start time.Time, err error, args ...any) {
+	latency := time.Since(start)
+	code := errorCode(err)
+	f.metrics.RequestDone(op, latency, code)
+
+	args = append(args, "op", op, "duration", latency)
+	if err == nil {
+		f.logger.Log(ctx, slog.LevelDebug, "s3fs: request", args...)
+		return
+	}
+
+	level := slog.LevelError
+	if errors.Is(toFSError(err), os.ErrNotExist) {
+		level = slog.LevelDebug
+	}
+	f.logger.Log(ctx, level, "s3fs: request failed", append(args, "code", code, "error", err)...)
+}
+
+// cacheLookup reports a lookup of the compressed variant of the key in the cache of
+// WithCompression to the Metrics and the Logger of the FileSystem
+func (f FileSystem) cacheLookup(ctx context.Context, key, encoding string, hit bool) {
+	f.metrics.CacheLookup(hit)
+
+	msg := "s3fs: cache miss"
+	if hit {
+		msg = "s3fs: cache hit"
+	}
+	f.logger.Log(ctx, slog.LevelDebug, msg, "key", key, "encoding", encoding)
+}
+
+// logRetries logs the requests that the S3 client is about to retry
+func (f FileSystem) logRetries(r *request.Request) {
+	if !r.WillRetry() {
+		return
+	}
+
+	f.logger.Log(r.Context(), slog.LevelWarn, "s3fs: retrying request",
+		"op", r.Operation.Name, "bucket", f.bucket, "attempt", r.RetryCount+1, "error", r.Error)
+}
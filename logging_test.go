@@ -0,0 +1,63 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestRequestDoneLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	s3Fs := New("public-sample-data", "us-east-1", WithLogger(logger))
+
+	cases := []struct {
+		err   error
+		level string
+	}{
+		{nil, "level=DEBUG msg=\"s3fs: request\""},
+		{awserr.New(s3.ErrCodeNoSuchKey, "missing", nil), "level=DEBUG msg=\"s3fs: request failed\""},
+		{errors.New("connection reset"), "level=ERROR msg=\"s3fs: request failed\""},
+	}
+
+	for _, c := range cases {
+		buf.Reset()
+		s3Fs.requestDone(context.Background(), "GetObject", time.Now(), c.err, "key", "passengers.txt")
+
+		line := buf.String()
+		if !strings.Contains(line, c.level) || !strings.Contains(line, "key=passengers.txt") || !strings.Contains(line, "op=GetObject") {
+			t.Fatalf("error: unexpected log line %q for %v", line, c.err)
+		}
+	}
+}
+
+func TestCacheLookupLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	m := &recordingMetrics{}
+	s3Fs := New("public-sample-data", "us-east-1", WithLogger(logger), WithMetrics(m))
+
+	for _, hit := range []bool{false, true} {
+		buf.Reset()
+		s3Fs.cacheLookup(context.Background(), "index.html", "br", hit)
+
+		want := "level=DEBUG msg=\"s3fs: cache miss\""
+		if hit {
+			want = "level=DEBUG msg=\"s3fs: cache hit\""
+		}
+		line := buf.String()
+		if !strings.Contains(line, want) || !strings.Contains(line, "key=index.html") || !strings.Contains(line, "encoding=br") {
+			t.Fatalf("error: unexpected log line %q for hit %v", line, hit)
+		}
+	}
+	if m.hits != 1 || m.misses != 1 {
+		t.Fatalf("error: expected a miss and a hit, got %d misses and %d hits", m.misses, m.hits)
+	}
+}
@@ -0,0 +1,154 @@
+package s3fs
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Metadata is the metadata of an object changed by UpdateMetadata
+type Metadata struct {
+	CacheControl       string
+	ContentDisposition string
+	ContentEncoding    string
+	ContentLanguage    string
+	ContentType        string
+	// User is the user metadata, the X-Amz-Meta-* headers
+	User map[string]string
+}
+
+// UpdateMetadata changes the metadata of the object with the name without transferring its data,
+// by copying the object onto itself with CopyObject and the REPLACE metadata directive. The
+// non-empty fields of meta replace those of the object and the rest are kept. Entries of
+// meta.User are added to the user metadata, or removed if their value is empty. The storage
+// class and the tags of the object are kept, except that objects over 5GB, which are copied
+// in parts, lose their tags. The object gets a new LastModified.
+func (f FileSystem) UpdateMetadata(name string, meta Metadata) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	head, err := f.headObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+
+	set := func(dst **string, value string) {
+		if value != "" {
+			*dst = aws.String(value)
+		}
+	}
+	set(&head.CacheControl, meta.CacheControl)
+	set(&head.ContentDisposition, meta.ContentDisposition)
+	set(&head.ContentEncoding, meta.ContentEncoding)
+	set(&head.ContentLanguage, meta.ContentLanguage)
+	set(&head.ContentType, meta.ContentType)
+
+	if len(meta.User) > 0 && head.Metadata == nil {
+		head.Metadata = map[string]*string{}
+	}
+	for k, value := range meta.User {
+		// S3 returns metadata names capitalized, so they are replaced whatever their case
+		for existing := range head.Metadata {
+			if http.CanonicalHeaderKey(existing) == http.CanonicalHeaderKey(k) {
+				delete(head.Metadata, existing)
+			}
+		}
+		if value != "" {
+			head.Metadata[k] = aws.String(value)
+		}
+	}
+
+	if aws.Int64Value(head.ContentLength) > maxCopyObjectSize {
+		return f.multipartCopy(ctx, key, key, head)
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:             aws.String(f.bucket),
+		Key:                aws.String(key),
+		CopySource:         aws.String(copySource(f.bucket, key)),
+		CopySourceIfMatch:  head.ETag,
+		MetadataDirective:  aws.String(s3.MetadataDirectiveReplace),
+		CacheControl:       head.CacheControl,
+		ContentDisposition: head.ContentDisposition,
+		ContentEncoding:    head.ContentEncoding,
+		ContentLanguage:    head.ContentLanguage,
+		ContentType:        head.ContentType,
+		Metadata:           head.Metadata,
+		StorageClass:       head.StorageClass,
+	}
+	if expires, err := http.ParseTime(aws.StringValue(head.Expires)); err == nil {
+		input.Expires = aws.Time(expires)
+	}
+
+	start := time.Now()
+	_, err = f.s3.CopyObjectWithContext(ctx, input)
+	f.requestDone(ctx, "CopyObject", start, err, "key", key, "source", key)
+	f.staging.drop(key)
+
+	return toFSError(err)
+}
+
+// GetTags returns the tags of the object with the name
+func (f FileSystem) GetTags(name string) (map[string]string, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	out, err := f.s3.GetObjectTaggingWithContext(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	f.requestDone(ctx, "GetObjectTagging", start, err, "key", key)
+	if err != nil {
+		return nil, toFSError(err)
+	}
+
+	tags := make(map[string]string, len(out.TagSet))
+	for _, tag := range out.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// SetTags replaces the tags of the object with the name with tags. S3 allows at most 10 tags.
+func (f FileSystem) SetTags(name string, tags map[string]string) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tagSet := make([]*s3.Tag, 0, len(tags))
+	for _, k := range keys {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	_, err = f.s3.PutObjectTaggingWithContext(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(f.bucket),
+		Key:     aws.String(key),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	f.requestDone(ctx, "PutObjectTagging", start, err, "key", key)
+
+	return toFSError(err)
+}
@@ -0,0 +1,91 @@
+package s3fs
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestUpdateMetadata(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv)
+
+	if err := s3Fs.WriteFile("app.js", []byte("console.log('app')"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := s3Fs.SetTags("app.js", map[string]string{"team": "web"}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	err := s3Fs.UpdateMetadata("app.js", Metadata{
+		CacheControl: "max-age=31536000, immutable",
+		User:         map[string]string{"release": "42"},
+	})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	f, err := s3Fs.Open("app.js")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	file := f.(*File)
+	if file.Header().Get("Cache-Control") != "max-age=31536000, immutable" || file.Header().Get("X-Amz-Meta-Release") != "42" {
+		t.Fatalf("error: metadata wasn't updated, got %v", file.Header())
+	}
+	if file.ContentType() != "text/javascript; charset=utf-8" {
+		t.Fatalf("error: expected the content type to be kept, got %s", file.ContentType())
+	}
+
+	tags, err := s3Fs.GetTags("app.js")
+	if err != nil || !reflect.DeepEqual(tags, map[string]string{"team": "web"}) {
+		t.Fatalf("error: expected the tags to be kept, got %v, %v", tags, err)
+	}
+
+	if err := s3Fs.UpdateMetadata("app.js", Metadata{User: map[string]string{"Release": ""}}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	f, _ = s3Fs.Open("app.js")
+	defer f.Close()
+	if v := f.(*File).Header().Get("X-Amz-Meta-Release"); v != "" {
+		t.Fatalf("error: expected the metadata to be removed, got %s", v)
+	}
+}
+
+func TestUpdateMetadataMultipart(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv)
+
+	// copy the object in parts like one over 5GB
+	defer func(size int64) { maxCopyObjectSize = size }(maxCopyObjectSize)
+	maxCopyObjectSize = 4
+
+	expires := "Thu, 01 Jan 2037 00:00:00 GMT"
+	srv.PutObjectWithHeader("public-sample-data", "app.js", []byte("console.log('app')"), http.Header{
+		"Content-Type": {"text/javascript"},
+		"Expires":      {expires},
+	})
+
+	if err := s3Fs.UpdateMetadata("app.js", Metadata{CacheControl: "no-cache"}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	f, err := s3Fs.Open("app.js")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	file := f.(*File)
+	if file.Header().Get("Cache-Control") != "no-cache" || file.Header().Get("Expires") != expires {
+		t.Fatalf("error: expected Cache-Control and Expires to be kept, got %v", file.Header())
+	}
+	if file.ContentType() != "text/javascript" {
+		t.Fatalf("error: expected the content type to be kept, got %s", file.ContentType())
+	}
+	if data, _ := srv.Object("public-sample-data", "app.js"); string(data) != "console.log('app')" {
+		t.Fatalf("error: stored %q", data)
+	}
+}
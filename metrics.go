@@ -0,0 +1,65 @@
+package s3fs
+
+import (
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Metrics receives measurements from a FileSystem. See WithMetrics. Implementations must be
+// safe for concurrent use; the s3fsprom package provides one backed by Prometheus collectors.
+type Metrics interface {
+	// ObjectOpened is called every time an object is opened
+	ObjectOpened()
+	// BytesDownloaded is called with the number of bytes read from the body of an object
+	BytesDownloaded(n int64)
+	// RequestDone is called after every S3 request with the name of the operation, such as
+	// "GetObject", its latency and the S3 error code, which is empty if the request succeeded
+	RequestDone(op string, latency time.Duration, errCode string)
+	// CacheLookup is called for every lookup in the cache of compressed variants of
+	// WithCompression, with whether the variant was cached
+	CacheLookup(hit bool)
+}
+
+// WithMetrics sets the Metrics that receive measurements of the FileSystem
+func WithMetrics(m Metrics) Option {
+	return func(f *FileSystem) {
+		f.metrics = m
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObjectOpened()                             {}
+func (nopMetrics) BytesDownloaded(int64)                     {}
+func (nopMetrics) RequestDone(string, time.Duration, string) {}
+func (nopMetrics) CacheLookup(bool)                          {}
+
+// errorCode returns the S3 error code of err, "Unknown" for errors without one and "" for nil
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+
+	return "Unknown"
+}
+
+// countingBody reports the bytes read from an object body to Metrics
+type countingBody struct {
+	io.ReadCloser
+	metrics Metrics
+}
+
+func (b countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.metrics.BytesDownloaded(int64(n))
+	}
+
+	return n, err
+}
@@ -0,0 +1,63 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type recordingMetrics struct {
+	opens  int
+	bytes  int64
+	codes  []string
+	hits   int
+	misses int
+}
+
+func (m *recordingMetrics) ObjectOpened()           { m.opens++ }
+func (m *recordingMetrics) BytesDownloaded(n int64) { m.bytes += n }
+func (m *recordingMetrics) RequestDone(op string, latency time.Duration, errCode string) {
+	m.codes = append(m.codes, errCode)
+}
+func (m *recordingMetrics) CacheLookup(hit bool) {
+	if hit {
+		m.hits++
+	} else {
+		m.misses++
+	}
+}
+
+func TestCountingBody(t *testing.T) {
+	m := &recordingMetrics{}
+	body := countingBody{ReadCloser: io.NopCloser(strings.NewReader("0123456789")), metrics: m}
+
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		t.Fatalf("error: reading body: %s", err)
+	}
+
+	if m.bytes != 10 {
+		t.Fatalf("error: counted %d bytes, want 10", m.bytes)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{awserr.New(s3.ErrCodeNoSuchKey, "missing", nil), s3.ErrCodeNoSuchKey},
+		{errors.New("connection reset"), "Unknown"},
+	}
+
+	for _, c := range cases {
+		if got := errorCode(c.err); got != c.want {
+			t.Fatalf("error: code of %v is %q, want %q", c.err, got, c.want)
+		}
+	}
+}
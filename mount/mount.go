@@ -0,0 +1,464 @@
+//go:build linux || darwin || freebsd
+
+// Package mount exposes an s3fs.FileSystem as a FUSE file system.
+//
+// Objects are read with ranged GetObject requests through s3fs.File, directories are the
+// common prefixes of the keys, and written files are buffered in memory and stored with
+// WriteFile when they are flushed. The FileSystem must map names to whole keys, like
+// s3fs.PathKeyMapper does.
+package mount
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/shijuleon/s3fs"
+)
+
+// Options configures Mount
+type Options struct {
+	// ReadOnly refuses every change to the bucket
+	ReadOnly bool
+	// AttrTimeout is how long the kernel caches the attributes and entries, 1s by default
+	AttrTimeout time.Duration
+	// Debug logs every FUSE request
+	Debug bool
+}
+
+// Mount mounts fsys at dir. The returned server is already serving; call Wait to block
+// until the file system is unmounted and Unmount to unmount it.
+func Mount(dir string, fsys *s3fs.FileSystem, opts Options) (*fuse.Server, error) {
+	timeout := opts.AttrTimeout
+	if timeout == 0 {
+		timeout = time.Second
+	}
+
+	root := &node{fsys: fsys, readOnly: opts.ReadOnly}
+	return fs.Mount(dir, root, &fs.Options{
+		AttrTimeout:  &timeout,
+		EntryTimeout: &timeout,
+		MountOptions: fuse.MountOptions{
+			FsName: "s3fs",
+			Name:   "s3fs",
+			Debug:  opts.Debug,
+		},
+	})
+}
+
+// node is a file or a directory. Its name is found from its position in the tree rather
+// than stored, so that it follows renames.
+type node struct {
+	fs.Inode
+
+	fsys     *s3fs.FileSystem
+	readOnly bool
+
+	mu     sync.Mutex
+	writer *writeHandle
+}
+
+var (
+	_ fs.NodeLookuper  = (*node)(nil)
+	_ fs.NodeReaddirer = (*node)(nil)
+	_ fs.NodeGetattrer = (*node)(nil)
+	_ fs.NodeSetattrer = (*node)(nil)
+	_ fs.NodeOpener    = (*node)(nil)
+	_ fs.NodeCreater   = (*node)(nil)
+	_ fs.NodeMkdirer   = (*node)(nil)
+	_ fs.NodeUnlinker  = (*node)(nil)
+	_ fs.NodeRmdirer   = (*node)(nil)
+	_ fs.NodeRenamer   = (*node)(nil)
+)
+
+// name returns the name of the node for the FileSystem
+func (n *node) name() string {
+	return "/" + n.Path(nil)
+}
+
+func (n *node) childName(child string) string {
+	if p := n.Path(nil); p != "" {
+		return "/" + p + "/" + child
+	}
+	return "/" + child
+}
+
+func (n *node) newChild(ctx context.Context, info os.FileInfo) *fs.Inode {
+	mode := uint32(syscall.S_IFREG)
+	if info.IsDir() {
+		mode = syscall.S_IFDIR
+	}
+
+	return n.NewInode(ctx, &node{fsys: n.fsys, readOnly: n.readOnly}, fs.StableAttr{Mode: mode})
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	info, err := n.fsys.StatContext(ctx, n.childName(name))
+	if err != nil {
+		return nil, toErrno(err)
+	}
+
+	n.setAttr(info, &out.Attr)
+	return n.newChild(ctx, info), 0
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	dir, err := n.fsys.OpenContext(ctx, n.name())
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	defer dir.Close()
+
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		return nil, toErrno(err)
+	}
+
+	entries := make([]fuse.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		mode := uint32(syscall.S_IFREG)
+		if info.IsDir() {
+			mode = syscall.S_IFDIR
+		}
+		entries = append(entries, fuse.DirEntry{Name: info.Name(), Mode: mode})
+	}
+
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *node) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if w := n.openWriter(); w != nil {
+		w.setAttr(&out.Attr)
+		return 0
+	}
+
+	if n.IsRoot() {
+		out.Mode = syscall.S_IFDIR | n.perm(0755)
+		return 0
+	}
+
+	info, err := n.fsys.StatContext(ctx, n.name())
+	if err != nil {
+		return toErrno(err)
+	}
+
+	n.setAttr(info, &out.Attr)
+	return 0
+}
+
+// Setattr only supports truncating a file open for writing; changes of mode, owner and
+// times are accepted and ignored since S3 doesn't store them
+func (n *node) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if size, ok := in.GetSize(); ok {
+		w := n.openWriter()
+		if w == nil {
+			return syscall.EPERM
+		}
+		w.truncate(int64(size))
+	}
+
+	return n.Getattr(ctx, fh, out)
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) == 0 {
+		file, err := n.fsys.OpenContext(ctx, n.name())
+		if err != nil {
+			return nil, 0, toErrno(err)
+		}
+		if info, err := file.Stat(); err == nil && info.Size() == s3fs.SizeUnknown {
+			// the kernel would stop reading at the size of the attributes, which is the
+			// size of the stored bytes rather than of the content
+			return &readHandle{file: file}, fuse.FOPEN_DIRECT_IO, 0
+		}
+		return &readHandle{file: file}, 0, 0
+	}
+
+	if n.readOnly {
+		return nil, 0, syscall.EROFS
+	}
+
+	w := &writeHandle{node: n, name: n.name()}
+	if flags&syscall.O_TRUNC == 0 {
+		// S3 can't change part of an object, so the whole object is rewritten on flush
+		data, err := s3fs.ReadFile(n.fsys, w.name)
+		if err != nil {
+			return nil, 0, toErrno(err)
+		}
+		w.buf.Write(data)
+	}
+	w.dirty = flags&syscall.O_TRUNC != 0
+
+	n.setWriter(w)
+	return w, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	if n.readOnly {
+		return nil, nil, 0, syscall.EROFS
+	}
+
+	child := &node{fsys: n.fsys, readOnly: n.readOnly}
+	inode := n.NewInode(ctx, child, fs.StableAttr{Mode: syscall.S_IFREG})
+
+	w := &writeHandle{node: child, name: n.childName(name), dirty: true}
+	if errno := w.Flush(ctx); errno != 0 {
+		return nil, nil, 0, errno
+	}
+	child.setWriter(w)
+
+	w.setAttr(&out.Attr)
+	return inode, w, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if n.readOnly {
+		return nil, syscall.EROFS
+	}
+
+	if err := n.fsys.Mkdir(n.childName(name)); err != nil {
+		return nil, toErrno(err)
+	}
+
+	out.Mode = syscall.S_IFDIR | n.perm(0755)
+	child := &node{fsys: n.fsys, readOnly: n.readOnly}
+	return n.NewInode(ctx, child, fs.StableAttr{Mode: syscall.S_IFDIR}), 0
+}
+
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	if n.readOnly {
+		return syscall.EROFS
+	}
+
+	return toErrno(n.fsys.Remove(n.childName(name)))
+}
+
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if n.readOnly {
+		return syscall.EROFS
+	}
+
+	dir, err := n.fsys.OpenContext(ctx, n.childName(name))
+	if err != nil {
+		return toErrno(err)
+	}
+	entries, err := dir.Readdir(1)
+	dir.Close()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return toErrno(err)
+	}
+	if len(entries) > 0 {
+		return syscall.ENOTEMPTY
+	}
+
+	// removes the folder marker, if any
+	return toErrno(n.fsys.RemoveAll(n.childName(name)))
+}
+
+func (n *node) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if n.readOnly {
+		return syscall.EROFS
+	}
+
+	child := n.GetChild(name)
+	if child != nil && child.IsDir() {
+		// renaming a prefix means copying every object under it, so let mv
+		// fall back to copying and removing the files one by one
+		return syscall.EXDEV
+	}
+
+	parent := newParent.EmbeddedInode().Operations().(*node)
+	return toErrno(n.fsys.Move(n.childName(name), parent.childName(newName)))
+}
+
+func (n *node) openWriter() *writeHandle {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.writer
+}
+
+func (n *node) setWriter(w *writeHandle) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.writer = w
+}
+
+func (n *node) perm(mode uint32) uint32 {
+	if n.readOnly {
+		return mode &^ 0222
+	}
+	return mode
+}
+
+func (n *node) setAttr(info os.FileInfo, out *fuse.Attr) {
+	if info.IsDir() {
+		out.Mode = syscall.S_IFDIR | n.perm(0755)
+		return
+	}
+
+	out.Mode = syscall.S_IFREG | n.perm(0644)
+	out.Size = uint64(info.Size())
+	modTime := info.ModTime()
+	out.SetTimes(nil, &modTime, &modTime)
+}
+
+// readHandle reads an object with a File, which reopens its body with a ranged GetObject
+// when a read doesn't continue where the previous one ended. Files of unknown size, such as
+// decompressed objects, can only be read sequentially until io.EOF.
+type readHandle struct {
+	mu     sync.Mutex
+	file   http.File
+	offset int64
+}
+
+var (
+	_ fs.FileReader   = (*readHandle)(nil)
+	_ fs.FileReleaser = (*readHandle)(nil)
+)
+
+func (h *readHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	info, err := h.file.Stat()
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	if size := info.Size(); size != s3fs.SizeUnknown {
+		if off >= size {
+			return fuse.ReadResultData(nil), 0
+		}
+		if remaining := size - off; int64(len(dest)) > remaining {
+			dest = dest[:remaining]
+		}
+		if _, err := h.file.Seek(off, io.SeekStart); err != nil {
+			return nil, toErrno(err)
+		}
+	} else if off != h.offset {
+		return nil, syscall.ESPIPE
+	}
+
+	n, err := io.ReadFull(h.file, dest)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, toErrno(err)
+	}
+	h.offset = off + int64(n)
+
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+func (h *readHandle) Release(ctx context.Context) syscall.Errno {
+	return toErrno(h.file.Close())
+}
+
+// writeHandle buffers a file open for writing and stores it with WriteFile when flushed
+type writeHandle struct {
+	node *node
+	name string
+
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	dirty bool
+}
+
+var (
+	_ fs.FileReader   = (*writeHandle)(nil)
+	_ fs.FileWriter   = (*writeHandle)(nil)
+	_ fs.FileFlusher  = (*writeHandle)(nil)
+	_ fs.FileReleaser = (*writeHandle)(nil)
+)
+
+func (h *writeHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data := h.buf.Bytes()
+	if off >= int64(len(data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+
+	n := copy(dest, data[off:])
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+func (h *writeHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	end := off + int64(len(data))
+	if grow := end - int64(h.buf.Len()); grow > 0 {
+		h.buf.Write(make([]byte, grow))
+	}
+	copy(h.buf.Bytes()[off:], data)
+	h.dirty = true
+
+	return uint32(len(data)), 0
+}
+
+func (h *writeHandle) truncate(size int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if grow := size - int64(h.buf.Len()); grow > 0 {
+		h.buf.Write(make([]byte, grow))
+	} else {
+		h.buf.Truncate(int(size))
+	}
+	h.dirty = true
+}
+
+func (h *writeHandle) Flush(ctx context.Context) syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.dirty {
+		return 0
+	}
+
+	if err := h.node.fsys.WriteFile(h.name, h.buf.Bytes(), ""); err != nil {
+		return toErrno(err)
+	}
+	h.dirty = false
+	return 0
+}
+
+func (h *writeHandle) Release(ctx context.Context) syscall.Errno {
+	errno := h.Flush(ctx)
+	h.node.setWriter(nil)
+	return errno
+}
+
+func (h *writeHandle) setAttr(out *fuse.Attr) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out.Mode = syscall.S_IFREG | 0644
+	out.Size = uint64(h.buf.Len())
+	now := time.Now()
+	out.SetTimes(nil, &now, &now)
+}
+
+// toErrno returns the errno for an error of the FileSystem
+func toErrno(err error) syscall.Errno {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, os.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, os.ErrPermission), errors.Is(err, s3fs.ErrAccessDenied):
+		return syscall.EACCES
+	case errors.Is(err, s3fs.ErrObjectChanged):
+		return syscall.ESTALE
+	case errors.Is(err, context.Canceled):
+		return syscall.EINTR
+	}
+	return syscall.EIO
+}
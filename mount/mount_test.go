@@ -0,0 +1,248 @@
+//go:build linux || darwin || freebsd
+
+package mount
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/shijuleon/s3fs"
+	"github.com/shijuleon/s3fs/s3test"
+)
+
+// newTestRoot returns the root node of a tree for the bucket of an s3test.Server, set up
+// like Mount does but without mounting it
+func newTestRoot(t *testing.T, readOnly bool, opts ...s3fs.Option) (*node, *s3test.Server) {
+	srv := s3test.NewServer()
+	t.Cleanup(srv.Close)
+	srv.PutObject("site", "docs/setup.html", []byte("<h1>Setup</h1>"), "text/html")
+	srv.PutObject("site", "docs/img/logo.png", []byte("png"), "image/png")
+	srv.PutObject("site", "index.html", []byte("<h1>Home</h1>"), "text/html")
+
+	opts = append([]s3fs.Option{s3fs.WithEndpoint(srv.URL), s3fs.WithAnonymousCredentials(), s3fs.WithKeyMapper(s3fs.PathKeyMapper)}, opts...)
+	root := &node{fsys: s3fs.New("site", "us-east-1", opts...), readOnly: readOnly}
+	fs.NewNodeFS(root, &fs.Options{})
+	return root, srv
+}
+
+// lookup looks up the child of parent with the name and adds it to the tree
+func lookup(t *testing.T, parent *node, name string) *node {
+	var out fuse.EntryOut
+	inode, errno := parent.Lookup(context.Background(), name, &out)
+	if errno != 0 {
+		t.Fatalf("error: looking up %s: %v", name, errno)
+	}
+	parent.AddChild(name, inode, true)
+	return inode.Operations().(*node)
+}
+
+// read reads size bytes at off from the handle
+func read(t *testing.T, h fs.FileReader, size int, off int64) []byte {
+	res, errno := h.Read(context.Background(), make([]byte, size), off)
+	if errno != 0 {
+		t.Fatalf("error: reading at %d: %v", off, errno)
+	}
+	data, _ := res.Bytes(nil)
+	return data
+}
+
+func TestLookupReaddir(t *testing.T) {
+	root, _ := newTestRoot(t, false)
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	if _, errno := root.Lookup(ctx, "missing.html", &out); errno != syscall.ENOENT {
+		t.Fatalf("error: expected ENOENT, got %v", errno)
+	}
+
+	docs := lookup(t, root, "docs")
+	if !docs.IsDir() {
+		t.Fatalf("error: expected docs to be a directory")
+	}
+	setup := lookup(t, docs, "setup.html")
+	var attr fuse.AttrOut
+	if errno := setup.Getattr(ctx, nil, &attr); errno != 0 || attr.Size != 14 || attr.Mode != syscall.S_IFREG|0644 {
+		t.Fatalf("error: unexpected attributes %+v, %v", attr.Attr, errno)
+	}
+
+	stream, errno := docs.Readdir(ctx)
+	if errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	var names []string
+	for stream.HasNext() {
+		entry, _ := stream.Next()
+		names = append(names, entry.Name)
+	}
+	if len(names) != 2 || names[0] != "img" || names[1] != "setup.html" {
+		t.Fatalf("error: unexpected entries %v", names)
+	}
+}
+
+func TestReadHandle(t *testing.T) {
+	root, _ := newTestRoot(t, false)
+	setup := lookup(t, lookup(t, root, "docs"), "setup.html")
+
+	h, _, errno := setup.Open(context.Background(), syscall.O_RDONLY)
+	if errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	defer h.(fs.FileReleaser).Release(context.Background())
+
+	if data := read(t, h.(fs.FileReader), 5, 4); string(data) != "Setup" {
+		t.Fatalf("error: read %q", data)
+	}
+	if data := read(t, h.(fs.FileReader), 100, 0); string(data) != "<h1>Setup</h1>" {
+		t.Fatalf("error: read %q", data)
+	}
+	if data := read(t, h.(fs.FileReader), 100, 14); len(data) != 0 {
+		t.Fatalf("error: expected nothing at the end of the file, read %q", data)
+	}
+}
+
+func TestReadHandleSizeUnknown(t *testing.T) {
+	root, srv := newTestRoot(t, false, s3fs.WithDecompression())
+	content := bytes.Repeat([]byte("compressed "), 100)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(content)
+	zw.Close()
+	srv.PutObjectWithHeader("site", "app.js", buf.Bytes(), http.Header{"Content-Type": {"text/javascript"}, "Content-Encoding": {"gzip"}})
+
+	app := lookup(t, root, "app.js")
+	h, flags, errno := app.Open(context.Background(), syscall.O_RDONLY)
+	if errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	defer h.(fs.FileReleaser).Release(context.Background())
+	if flags&fuse.FOPEN_DIRECT_IO == 0 {
+		t.Fatalf("error: expected direct I/O for a file of unknown size")
+	}
+
+	var got []byte
+	for {
+		data := read(t, h.(fs.FileReader), 256, int64(len(got)))
+		if len(data) == 0 {
+			break
+		}
+		got = append(got, data...)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("error: read %d bytes, want %d", len(got), len(content))
+	}
+
+	if _, errno := h.(fs.FileReader).Read(context.Background(), make([]byte, 10), 0); errno != syscall.ESPIPE {
+		t.Fatalf("error: expected ESPIPE reading backwards, got %v", errno)
+	}
+}
+
+func TestWriteHandle(t *testing.T) {
+	root, srv := newTestRoot(t, false)
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	inode, h, _, errno := root.Create(ctx, "notes.txt", syscall.O_WRONLY, 0644, &out)
+	if errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	root.AddChild("notes.txt", inode, true)
+	w := h.(*writeHandle)
+	w.Write(ctx, []byte("world"), 6)
+	w.Write(ctx, []byte("hello"), 0)
+	if errno := w.Release(ctx); errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	if data, _ := srv.Object("site", "notes.txt"); !bytes.Equal(data, []byte("hello\x00world")) {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	// without O_TRUNC, the object is rewritten with the changes
+	notes := inode.Operations().(*node)
+	h, _, errno = notes.Open(ctx, syscall.O_RDWR)
+	if errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	h.(*writeHandle).Write(ctx, []byte(" "), 5)
+	var attr fuse.AttrOut
+	if errno := notes.Getattr(ctx, h, &attr); errno != 0 || attr.Size != 11 {
+		t.Fatalf("error: expected the size of the open file, got %d, %v", attr.Size, errno)
+	}
+	h.(*writeHandle).Release(ctx)
+	if data, _ := srv.Object("site", "notes.txt"); string(data) != "hello world" {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	h, _, _ = notes.Open(ctx, syscall.O_WRONLY|syscall.O_TRUNC)
+	h.(*writeHandle).Release(ctx)
+	if data, ok := srv.Object("site", "notes.txt"); !ok || len(data) != 0 {
+		t.Fatalf("error: expected O_TRUNC to empty the object, stored %q", data)
+	}
+}
+
+func TestRenameRemove(t *testing.T) {
+	root, srv := newTestRoot(t, false, s3fs.WithFolderMarkers())
+	ctx := context.Background()
+
+	docs := lookup(t, root, "docs")
+	if errno := root.Rename(ctx, "index.html", docs, "home.html", 0); errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	if _, ok := srv.Object("site", "index.html"); ok {
+		t.Fatalf("error: expected Rename to remove the old object")
+	}
+	if data, _ := srv.Object("site", "docs/home.html"); string(data) != "<h1>Home</h1>" {
+		t.Fatalf("error: stored %q", data)
+	}
+	if errno := root.Rename(ctx, "docs", root, "documents", 0); errno != syscall.EXDEV {
+		t.Fatalf("error: expected EXDEV renaming a directory, got %v", errno)
+	}
+
+	if errno := root.Rmdir(ctx, "docs"); errno != syscall.ENOTEMPTY {
+		t.Fatalf("error: expected ENOTEMPTY, got %v", errno)
+	}
+	if errno := docs.Unlink(ctx, "home.html"); errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	if _, ok := srv.Object("site", "docs/home.html"); ok {
+		t.Fatalf("error: expected Unlink to remove the object")
+	}
+
+	var out fuse.EntryOut
+	if _, errno := root.Mkdir(ctx, "empty", 0755, &out); errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	if errno := root.Rmdir(ctx, "empty"); errno != 0 {
+		t.Fatalf("error: %v", errno)
+	}
+	if _, ok := srv.Object("site", "empty/"); ok {
+		t.Fatalf("error: expected Rmdir to remove the folder marker")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	root, _ := newTestRoot(t, true)
+	ctx := context.Background()
+
+	index := lookup(t, root, "index.html")
+	if _, _, errno := index.Open(ctx, syscall.O_WRONLY); errno != syscall.EROFS {
+		t.Fatalf("error: expected EROFS, got %v", errno)
+	}
+	var out fuse.EntryOut
+	if _, _, _, errno := root.Create(ctx, "new.txt", syscall.O_WRONLY, 0644, &out); errno != syscall.EROFS {
+		t.Fatalf("error: expected EROFS, got %v", errno)
+	}
+	if errno := root.Unlink(ctx, "index.html"); errno != syscall.EROFS {
+		t.Fatalf("error: expected EROFS, got %v", errno)
+	}
+
+	var attr fuse.AttrOut
+	if errno := index.Getattr(ctx, nil, &attr); errno != 0 || attr.Mode != syscall.S_IFREG|0444 {
+		t.Fatalf("error: expected read-only permissions, got %o, %v", attr.Mode, errno)
+	}
+}
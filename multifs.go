@@ -0,0 +1,83 @@
+package s3fs
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// MultiFS implements http.FileSystem by routing every Open to one of several FileSystems based
+// on the prefix of the path, so that a single handler can serve a namespace composed of
+// buckets in different regions. The prefix is removed from the path before it is passed to
+// the FileSystem of the route.
+type MultiFS struct {
+	routes []route
+}
+
+type route struct {
+	prefix string
+	fs     *FileSystem
+}
+
+// NewMultiFS creates a MultiFS without routes
+func NewMultiFS() *MultiFS {
+	return &MultiFS{}
+}
+
+// Route serves the paths under prefix, such as "/images/", from a new FileSystem for bucket in
+// region configured with opts, and returns that FileSystem
+func (m *MultiFS) Route(prefix, bucket, region string, opts ...Option) *FileSystem {
+	fs := New(bucket, region, opts...)
+	m.Handle(prefix, fs)
+	return fs
+}
+
+// Handle serves the paths under prefix from fs. When several prefixes match a path,
+// the longest one is used.
+func (m *MultiFS) Handle(prefix string, fs *FileSystem) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix != "/" {
+		prefix += "/"
+	}
+
+	m.routes = append(m.routes, route{prefix: prefix, fs: fs})
+	sort.SliceStable(m.routes, func(i, j int) bool {
+		return len(m.routes[i].prefix) > len(m.routes[j].prefix)
+	})
+}
+
+// Open opens name with the FileSystem of the longest matching prefix. It returns
+// os.ErrNotExist if there is no matching route.
+func (m *MultiFS) Open(name string) (http.File, error) {
+	return m.OpenContext(context.Background(), name)
+}
+
+// OpenContext is like Open and passes ctx to FileSystem.OpenContext
+func (m *MultiFS) OpenContext(ctx context.Context, name string) (http.File, error) {
+	fs, rest, ok := m.match(name)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return fs.OpenContext(ctx, rest)
+}
+
+// match returns the FileSystem for name and name relative to its prefix
+func (m *MultiFS) match(name string) (*FileSystem, string, bool) {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+
+	for _, r := range m.routes {
+		if name+"/" == r.prefix {
+			return r.fs, "/", true
+		}
+		if strings.HasPrefix(name, r.prefix) {
+			return r.fs, "/" + strings.TrimPrefix(name, r.prefix), true
+		}
+	}
+
+	return nil, "", false
+}
@@ -0,0 +1,37 @@
+package s3fs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMultiFSRouting(t *testing.T) {
+	m := NewMultiFS()
+	images := m.Route("/images/", "images-bucket", "us-east-1")
+	videos := m.Route("videos", "videos-bucket", "eu-west-1")
+	thumbnails := m.Route("/images/thumbnails/", "thumbnails-bucket", "us-east-1")
+
+	cases := []struct {
+		name string
+		fs   *FileSystem
+		rest string
+	}{
+		{"/images/logo.png", images, "/logo.png"},
+		{"/images/2024/logo.png", images, "/2024/logo.png"},
+		{"/images/thumbnails/logo.png", thumbnails, "/logo.png"},
+		{"/videos/intro.mp4", videos, "/intro.mp4"},
+		{"videos/intro.mp4", videos, "/intro.mp4"},
+		{"/videos", videos, "/"},
+	}
+
+	for _, c := range cases {
+		fs, rest, ok := m.match(c.name)
+		if !ok || fs != c.fs || rest != c.rest {
+			t.Fatalf("error: %s routed to %s %s, want %s %s", c.name, fs.bucket, rest, c.fs.bucket, c.rest)
+		}
+	}
+
+	if _, err := m.Open("/documents/report.pdf"); err != os.ErrNotExist {
+		t.Fatalf("error: expected os.ErrNotExist for an unrouted path, got %v", err)
+	}
+}
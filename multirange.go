@@ -0,0 +1,192 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrInvalidRange is returned when a requested range can't be satisfied by the object
+var ErrInvalidRange = errors.New("s3fs: invalid range")
+
+// MultiRangeFile holds several byte ranges of a single object. See FileSystem.OpenRanges
+type MultiRangeFile struct {
+	fs          FileSystem
+	key         string
+	size        int64
+	contentType string
+	etag        string
+	ranges      []FileRanges
+	// source is the key the ranges are read from, the content of pointer objects
+	source string
+	// cipher decrypts the ranges of encrypted objects
+	cipher *objectCipher
+}
+
+// OpenRanges returns a MultiRangeFile for the ranges of the object with the name. Each range is
+// an inclusive [start, end] pair; an end past the end of the object is truncated to the last byte.
+// The ranges are only fetched when the MultiRangeFile is encoded, one ranged GetObject per range.
+// With WithEncryption the ranges and the size are those of the decrypted object.
+func (f FileSystem) OpenRanges(name string, ranges [][2]int64) (*MultiRangeFile, error) {
+	if len(ranges) == 0 {
+		return nil, ErrInvalidRange
+	}
+
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	source, object, err := f.headContent(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	size := aws.Int64Value(object.ContentLength)
+	enc, err := f.openObjectCipher(ctx, object.Metadata, size)
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		size = plainSize(size)
+	}
+
+	fileRanges := make([]FileRanges, 0, len(ranges))
+	for _, r := range ranges {
+		start, end := r[0], r[1]
+		if start < 0 || end < start || start >= size {
+			return nil, ErrInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+		fileRanges = append(fileRanges, NewFileRanges(start, end))
+	}
+
+	return &MultiRangeFile{
+		fs:          f,
+		key:         key,
+		source:      source,
+		size:        size,
+		contentType: aws.StringValue(object.ContentType),
+		etag:        aws.StringValue(object.ETag),
+		ranges:      fileRanges,
+		cipher:      enc,
+	}, nil
+}
+
+// Name returns the base name of the object
+func (m *MultiRangeFile) Name() string {
+	return path.Base(m.key)
+}
+
+// Size returns the size of the whole object
+func (m *MultiRangeFile) Size() int64 {
+	return m.size
+}
+
+// ContentType returns the content type of the object as stored in S3
+func (m *MultiRangeFile) ContentType() string {
+	return m.contentType
+}
+
+// Ranges returns the validated ranges, with their ends clamped to the size of the object.
+// See FileRanges.Start and FileRanges.End.
+func (m *MultiRangeFile) Ranges() []FileRanges {
+	return m.ranges
+}
+
+// NewEncoder returns a ByteRangesEncoder for the object writing to w
+func (m *MultiRangeFile) NewEncoder(w io.Writer) *ByteRangesEncoder {
+	return NewByteRangesEncoder(w, m.contentType, m.size)
+}
+
+// Encode fetches every range in order and writes each one as a part to enc. It returns
+// ErrObjectChanged if the object was overwritten since OpenRanges. Encode doesn't close enc.
+func (m *MultiRangeFile) Encode(enc *ByteRangesEncoder) error {
+	for _, r := range m.ranges {
+		start, end := r.start, r.end
+		if m.cipher != nil {
+			start, end = m.cipher.cipherRange(r.start, r.end-r.start+1)
+		}
+
+		input := &s3.GetObjectInput{
+			Bucket: aws.String(m.fs.bucket),
+			Key:    aws.String(m.source),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		}
+		if m.etag != "" {
+			input.IfMatch = aws.String(m.etag)
+		}
+
+		object, err := m.fs.getObject(context.Background(), input)
+		if err != nil {
+			return err
+		}
+
+		body := object.Body
+		if m.cipher != nil {
+			body = m.cipher.decrypt(body, r.start)
+		}
+		err = enc.WritePart(r, body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ByteRangesEncoder writes a multipart/byteranges body as used by HTTP 206 responses
+// to requests for more than one range (RFC 7233, Appendix A)
+type ByteRangesEncoder struct {
+	mw          *multipart.Writer
+	contentType string
+	size        int64
+}
+
+// NewByteRangesEncoder creates a ByteRangesEncoder writing to w. contentType and size
+// describe the whole object and are repeated in the header of every part.
+func NewByteRangesEncoder(w io.Writer, contentType string, size int64) *ByteRangesEncoder {
+	return &ByteRangesEncoder{
+		mw:          multipart.NewWriter(w),
+		contentType: contentType,
+		size:        size,
+	}
+}
+
+// ContentType returns the value for the Content-Type header of the response
+func (e *ByteRangesEncoder) ContentType() string {
+	return "multipart/byteranges; boundary=" + e.mw.Boundary()
+}
+
+// WritePart writes body as the part for the range r
+func (e *ByteRangesEncoder) WritePart(r FileRanges, body io.Reader) error {
+	header := textproto.MIMEHeader{}
+	if e.contentType != "" {
+		header.Set("Content-Type", e.contentType)
+	}
+	header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, e.size))
+
+	part, err := e.mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(part, body, r.end-r.start+1)
+	return err
+}
+
+// Close writes the closing boundary
+func (e *ByteRangesEncoder) Close() error {
+	return e.mw.Close()
+}
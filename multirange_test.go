@@ -0,0 +1,71 @@
+package s3fs
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func TestByteRangesEncoder(t *testing.T) {
+	content := "0123456789abcdefghij"
+	ranges := []FileRanges{NewFileRanges(0, 4), NewFileRanges(10, 19)}
+
+	var buf bytes.Buffer
+	enc := NewByteRangesEncoder(&buf, "text/plain", int64(len(content)))
+	for _, r := range ranges {
+		if err := enc.WritePart(r, strings.NewReader(content[r.start:r.end+1])); err != nil {
+			t.Fatalf("error: writing part: %s", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("error: closing encoder: %s", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(enc.ContentType())
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("error: unexpected content type %q", enc.ContentType())
+	}
+
+	mr := multipart.NewReader(&buf, params["boundary"])
+	wantRanges := []string{"bytes 0-4/20", "bytes 10-19/20"}
+	wantBodies := []string{"01234", "abcdefghij"}
+	for i := range wantRanges {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("error: reading part %d: %s", i, err)
+		}
+		if got := part.Header.Get("Content-Range"); got != wantRanges[i] {
+			t.Fatalf("error: part %d Content-Range is %q, want %q", i, got, wantRanges[i])
+		}
+		if got := part.Header.Get("Content-Type"); got != "text/plain" {
+			t.Fatalf("error: part %d Content-Type is %q", i, got)
+		}
+		body, _ := io.ReadAll(part)
+		if string(body) != wantBodies[i] {
+			t.Fatalf("error: part %d body is %q, want %q", i, body, wantBodies[i])
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Fatalf("error: expected end of multipart body, got %v", err)
+	}
+}
+
+func TestOpenRangesRanges(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t))
+
+	m, err := s3Fs.OpenRanges("passengers.txt", [][2]int64{{0, 9}, {1000, 2000}})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	ranges := m.Ranges()
+	if len(ranges) != 2 || ranges[0].Start() != 0 || ranges[0].End() != 9 || ranges[1].Start() != 1000 || ranges[1].End() != 1045 {
+		t.Fatalf("error: unexpected ranges %v", ranges)
+	}
+	if _, err := s3Fs.OpenRanges("passengers.txt", [][2]int64{{1046, 1100}}); err != ErrInvalidRange {
+		t.Fatalf("error: expected ErrInvalidRange, got %v", err)
+	}
+}
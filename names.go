@@ -0,0 +1,104 @@
+package s3fs
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidName is returned for names rejected by the name normalization of
+// WithNameNormalization and FileServer
+var ErrInvalidName = errors.New("s3fs: invalid name")
+
+// NameOptions configures how names are normalized before they are mapped to keys. See
+// NormalizeName
+type NameOptions struct {
+	// Strict rejects suspicious names instead of cleaning them: names with backslashes, control
+	// characters, invalid UTF-8, empty or "." segments, or percent-encoded bytes, which are
+	// left in a request path that was encoded twice
+	Strict bool
+	// FoldCase lower-cases names, for buckets whose keys are all lower case
+	FoldCase bool
+	// Normalize, if set, is applied to names first. Use norm.NFC.String from
+	// golang.org/x/text/unicode/norm to match keys stored in Unicode normalization form C
+	// whatever form clients send.
+	Normalize func(string) string
+}
+
+// StrictNames are the NameOptions used by FileServer by default
+var StrictNames = NameOptions{Strict: true}
+
+// WithNameNormalization normalizes every name with NormalizeName before it is mapped to a key
+func WithNameNormalization(opts NameOptions) Option {
+	return func(f *FileSystem) {
+		f.names = &opts
+	}
+}
+
+// NormalizeName returns the cleaned name, with a leading slash, and keeps its trailing slash so
+// that WithIndexFile still applies. Names with a ".." segment are rejected with ErrInvalidName
+// rather than resolved, as are the suspicious names of NameOptions.Strict.
+func NormalizeName(name string, opts NameOptions) (string, error) {
+	if opts.Normalize != nil {
+		name = opts.Normalize(name)
+	}
+
+	if opts.Strict {
+		if err := checkName(name); err != nil {
+			return "", err
+		}
+	}
+
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", ErrInvalidName
+		}
+	}
+
+	if opts.FoldCase {
+		name = strings.ToLower(name)
+	}
+
+	cleaned := path.Clean("/" + name)
+	if strings.HasSuffix(name, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned, nil
+}
+
+// checkName rejects the suspicious names of NameOptions.Strict
+func checkName(name string) error {
+	if !utf8.ValidString(name) || strings.Contains(name, `\`) {
+		return ErrInvalidName
+	}
+	for i, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return ErrInvalidName
+		}
+		if r == '%' && i+2 < len(name) && isHex(name[i+1]) && isHex(name[i+2]) {
+			return ErrInvalidName
+		}
+	}
+
+	segments := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	for i, segment := range segments {
+		// a trailing slash leaves an empty last segment
+		if segment == "." || segment == "" && i < len(segments)-1 {
+			return ErrInvalidName
+		}
+	}
+	return nil
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// normalizeName normalizes name if WithNameNormalization is set
+func (f FileSystem) normalizeName(name string) (string, error) {
+	if f.names == nil {
+		return name, nil
+	}
+	return NormalizeName(name, *f.names)
+}
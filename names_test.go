@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	cases := []struct {
+		name string
+		opts NameOptions
+		want string
+	}{
+		{"img/logo.png", NameOptions{}, "/img/logo.png"},
+		{"//img/./logo.png", NameOptions{}, "/img/logo.png"},
+		{"/docs/", NameOptions{}, "/docs/"},
+		{"", NameOptions{}, "/"},
+		{"/100%.png", StrictNames, "/100%.png"},
+		{"/Img/Logo.PNG", NameOptions{FoldCase: true}, "/img/logo.png"},
+		{"/cafe\u0301.txt", NameOptions{Normalize: func(name string) string {
+			return strings.ReplaceAll(name, "e\u0301", "\u00e9")
+		}}, "/caf\u00e9.txt"},
+		{"/docs/", StrictNames, "/docs/"},
+	}
+	for _, c := range cases {
+		got, err := NormalizeName(c.name, c.opts)
+		if err != nil || got != c.want {
+			t.Fatalf("error: %q normalized to %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+
+	rejected := []struct {
+		name string
+		opts NameOptions
+	}{
+		{"/../../secret", NameOptions{}},
+		{"/img/../logo.png", NameOptions{}},
+		{"/img//logo.png", StrictNames},
+		{"/img/./logo.png", StrictNames},
+		{`/..\secret`, StrictNames},
+		{"/%2e%2e/secret", StrictNames},
+		{"/logo.png\x00.txt", StrictNames},
+		{"/\xff.png", StrictNames},
+	}
+	for _, c := range rejected {
+		if got, err := NormalizeName(c.name, c.opts); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("error: expected %q to be rejected, got %q, %v", c.name, got, err)
+		}
+	}
+}
+
+func TestNameNormalization(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t), WithKeyMapper(PathKeyMapper),
+		WithNameNormalization(NameOptions{FoldCase: true}))
+
+	f, err := s3Fs.Open("/Passengers.TXT")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	f.Close()
+
+	if _, err := s3Fs.Open("/../passengers.txt"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("error: expected ErrInvalidName, got %v", err)
+	}
+}
+
+func TestFileServerNames(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t), WithKeyMapper(PathKeyMapper))
+
+	codes := map[string]int{
+		"/passengers.txt":    http.StatusOK,
+		"/%252e%252e/secret": http.StatusBadRequest,
+		"/a/..%5csecret":     http.StatusBadRequest,
+	}
+	for target, want := range codes {
+		w := httptest.NewRecorder()
+		FileServer(s3Fs).ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Fatalf("error: %s got %d, want %d", target, w.Code, want)
+		}
+	}
+
+	// a request that bypasses the cleaning of http.ServeMux
+	r := httptest.NewRequest(http.MethodGet, "/passengers.txt", nil)
+	r.URL.Path = "/img/../../passengers.txt"
+	w := httptest.NewRecorder()
+	FileServer(s3Fs, WithNameOptions(NameOptions{})).ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("error: expected .. to be rejected without strict names, got %d", w.Code)
+	}
+}
@@ -0,0 +1,40 @@
+package s3fs
+
+// Option configures a FileSystem. See New
+type Option func(*FileSystem)
+
+// WithKeyMapper sets the KeyMapper used to find the key of the object for a name passed to Open.
+// By default only the base name of the path is used as the key.
+func WithKeyMapper(m KeyMapper) Option {
+	return func(f *FileSystem) {
+		f.keyMapper = m
+	}
+}
+
+// WithIndexFile makes Open serve the object name for paths ending in a slash, like the index
+// document of S3 static website hosting. For example "/" serves "index.html" and "/docs/"
+// serves "docs/index.html" when used with PathKeyMapper.
+func WithIndexFile(name string) Option {
+	return func(f *FileSystem) {
+		f.indexFile = name
+	}
+}
+
+// WithSPAFallback makes Open serve the index file at the root instead of failing with
+// os.ErrNotExist, so client-side routes of a single-page application load its entry point.
+// The index file is the one set with WithIndexFile, or index.html.
+func WithSPAFallback() Option {
+	return func(f *FileSystem) {
+		f.spaFallback = true
+	}
+}
+
+// WithFolderMarkers makes Mkdir create a zero-byte object with a key ending in a slash for the
+// directory, the "folder" objects created by the S3 console. Without it directories only exist
+// as long as there are objects under them and Mkdir doesn't write anything. Folder markers are
+// always treated as directories when opening and listing, whether or not the option is set.
+func WithFolderMarkers() Option {
+	return func(f *FileSystem) {
+		f.folderMarkers = true
+	}
+}
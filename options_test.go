@@ -0,0 +1,45 @@
+package s3fs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// recordingKeyMapper records the names it is asked to map and reports every object as missing
+func recordingKeyMapper(names *[]string) KeyMapper {
+	return func(urlPath string) (string, error) {
+		*names = append(*names, urlPath)
+		return "", os.ErrNotExist
+	}
+}
+
+func TestIndexFile(t *testing.T) {
+	var names []string
+	s3Fs := New("public-sample-data", "us-east-1", WithKeyMapper(recordingKeyMapper(&names)), WithIndexFile("index.html"))
+
+	for _, name := range []string{"/", "/docs/", "/docs/setup.html"} {
+		if _, err := s3Fs.Open(name); err != os.ErrNotExist {
+			t.Fatalf("error: expected os.ErrNotExist opening %s, got %v", name, err)
+		}
+	}
+
+	want := []string{"/index.html", "/", "/docs/index.html", "/docs/", "/docs/setup.html"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("error: opened %v, want %v", names, want)
+	}
+}
+
+func TestSPAFallback(t *testing.T) {
+	var names []string
+	s3Fs := New("public-sample-data", "us-east-1", WithKeyMapper(recordingKeyMapper(&names)), WithSPAFallback())
+
+	if _, err := s3Fs.Open("/app/settings"); err != os.ErrNotExist {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+
+	want := []string{"/app/settings", "/index.html"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("error: opened %v, want %v", names, want)
+	}
+}
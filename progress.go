@@ -0,0 +1,91 @@
+package s3fs
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressFunc is called with the number of bytes read or downloaded so far and the size of
+// the object, or SizeUnknown if it isn't known. See WithProgress
+type ProgressFunc func(transferred, total int64)
+
+// WithProgress makes the Files returned by Open and DownloadTo report their progress to fn,
+// at most once per interval and always when the last byte has been transferred. Every File
+// and every DownloadTo counts from zero, except that a resumed DownloadTo starts with the
+// chunks downloaded before. A zero interval reports every Read.
+func WithProgress(fn ProgressFunc, interval time.Duration) Option {
+	return func(f *FileSystem) {
+		f.progress = fn
+		f.progressInterval = interval
+	}
+}
+
+// progress counts the bytes transferred for a ProgressFunc. A nil *progress counts nothing.
+type progress struct {
+	fn       ProgressFunc
+	interval time.Duration
+
+	mu          sync.Mutex
+	transferred int64
+	total       int64
+	last        time.Time
+	finished    bool
+}
+
+// newProgress returns a progress for an object of size total of which transferred bytes
+// are already transferred, or nil without WithProgress
+func (f FileSystem) newProgress(total, transferred int64) *progress {
+	if f.progress == nil {
+		return nil
+	}
+
+	return &progress{fn: f.progress, interval: f.progressInterval, total: total, transferred: transferred}
+}
+
+// add counts n more bytes and calls the ProgressFunc if the interval passed or the
+// transfer is complete
+func (p *progress) add(n int64) {
+	if p == nil || n == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.transferred += n
+	now := time.Now()
+	if now.Sub(p.last) < p.interval && p.transferred != p.total {
+		return
+	}
+
+	p.last = now
+	p.fn(p.transferred, p.total)
+}
+
+// finish calls the ProgressFunc once at the end of a transfer of unknown size
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.finished {
+		p.finished = true
+		p.fn(p.transferred, p.total)
+	}
+}
+
+// progressReader counts the bytes read from r
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (r progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.add(int64(n))
+	return n, err
+}
@@ -0,0 +1,72 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// progressCalls records the calls of a ProgressFunc
+type progressCalls struct {
+	mu    sync.Mutex
+	calls [][2]int64
+}
+
+func (p *progressCalls) fn(transferred, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, [2]int64{transferred, total})
+}
+
+func (p *progressCalls) check(t *testing.T, size int64) {
+	if len(p.calls) == 0 {
+		t.Fatalf("error: ProgressFunc wasn't called")
+	}
+	for i := 1; i < len(p.calls); i++ {
+		if p.calls[i][0] < p.calls[i-1][0] {
+			t.Fatalf("error: progress went back from %d to %d", p.calls[i-1][0], p.calls[i][0])
+		}
+	}
+	if last := p.calls[len(p.calls)-1]; last != [2]int64{size, size} {
+		t.Fatalf("error: expected the last call to be %d of %d, got %v", size, size, last)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	calls := &progressCalls{}
+	s3Fs := newTestFileSystem(newTestServer(t), WithProgress(calls.fn, 0))
+
+	f, err := s3Fs.Open("test_dataset_large.json")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(io.Discard, struct{ io.Reader }{f}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	calls.check(t, 300<<10)
+	if len(calls.calls) < 2 {
+		t.Fatalf("error: expected a call for every Read, got %v", calls.calls)
+	}
+}
+
+func TestDownloadToProgress(t *testing.T) {
+	calls := &progressCalls{}
+	s3Fs := newTestFileSystem(newTestServer(t), WithProgress(calls.fn, time.Hour))
+
+	local := filepath.Join(t.TempDir(), "large.json")
+	err := s3Fs.DownloadTo(context.Background(), "test_dataset_large.json", local, DownloadOptions{ChunkSize: 64 << 10})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	calls.check(t, 300<<10)
+	// the first call and the last one, the interval suppresses the rest
+	if len(calls.calls) != 2 {
+		t.Fatalf("error: expected 2 calls, got %v", calls.calls)
+	}
+}
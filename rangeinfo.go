@@ -0,0 +1,73 @@
+package s3fs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RangeInfo describes the part of an object held by a ranged File
+type RangeInfo struct {
+	// Offset is the position of the first byte of the range in the object
+	Offset int64
+	// Length is the number of bytes in the range
+	Length int64
+	// TotalSize is the size of the whole object or -1 if it is unknown
+	TotalSize int64
+}
+
+// RangeInfo returns the range of the object held by the File as reported by the Content-Range
+// of the response. ok is false if the File holds the whole object.
+func (f *File) RangeInfo() (info RangeInfo, ok bool) {
+	if f.rangeInfo == nil {
+		return RangeInfo{}, false
+	}
+
+	return *f.rangeInfo, true
+}
+
+// parseContentRange parses a Content-Range header value of the form "bytes 0-99/1234"
+// or "bytes 0-99/*"
+func parseContentRange(s string) (RangeInfo, error) {
+	invalid := fmt.Errorf("s3fs: invalid Content-Range %q", s)
+
+	spec, ok := strings.CutPrefix(s, "bytes ")
+	if !ok {
+		return RangeInfo{}, invalid
+	}
+
+	byteRange, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RangeInfo{}, invalid
+	}
+
+	first, last, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return RangeInfo{}, invalid
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return RangeInfo{}, invalid
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return RangeInfo{}, invalid
+	}
+
+	info := RangeInfo{
+		Offset:    start,
+		Length:    end - start + 1,
+		TotalSize: -1,
+	}
+
+	if total != "*" {
+		info.TotalSize, err = strconv.ParseInt(total, 10, 64)
+		if err != nil || info.TotalSize <= end {
+			return RangeInfo{}, invalid
+		}
+	}
+
+	return info, nil
+}
@@ -0,0 +1,30 @@
+package s3fs
+
+import "testing"
+
+func TestParseContentRange(t *testing.T) {
+	cases := []struct {
+		header string
+		want   RangeInfo
+	}{
+		{"bytes 0-99/1234", RangeInfo{Offset: 0, Length: 100, TotalSize: 1234}},
+		{"bytes 1024-2048/300000000", RangeInfo{Offset: 1024, Length: 1025, TotalSize: 300000000}},
+		{"bytes 5-5/*", RangeInfo{Offset: 5, Length: 1, TotalSize: -1}},
+	}
+
+	for _, c := range cases {
+		info, err := parseContentRange(c.header)
+		if err != nil {
+			t.Fatalf("error: parsing %q: %s", c.header, err)
+		}
+		if info != c.want {
+			t.Fatalf("error: parsed %q as %+v, want %+v", c.header, info, c.want)
+		}
+	}
+
+	for _, header := range []string{"", "bytes */1234", "bytes 10-5/1234", "bytes 0-99/50", "items 0-1/2"} {
+		if _, err := parseContentRange(header); err == nil {
+			t.Fatalf("error: expected %q to be invalid", header)
+		}
+	}
+}
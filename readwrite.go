@@ -0,0 +1,119 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// WriteFileFS is implemented by file systems that can store a whole file at once, like FileSystem
+type WriteFileFS interface {
+	WriteFile(name string, data []byte, contentType string) error
+}
+
+// ReadFile reads the whole file with the name from fsys. The buffer is allocated once using the
+// size reported by Stat, so small objects such as configuration files and manifests are read
+// without the Open, Read and Close dance or repeated growing of the buffer.
+func ReadFile(fsys http.FileSystem, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := stat.Size()
+	if size < 0 {
+		return io.ReadAll(f)
+	}
+
+	data := make([]byte, size)
+	if size == 0 {
+		return data, nil
+	}
+
+	n, err := io.ReadFull(f, data)
+	return data[:n], err
+}
+
+// WriteFile stores data as the file with the name in fsys
+func WriteFile(fsys WriteFileFS, name string, data []byte, contentType string) error {
+	return fsys.WriteFile(name, data, contentType)
+}
+
+// WriteFile stores data as the object with the name using a single PutObject. If contentType
+// is empty, it is guessed from the extension of the name or else from the data. With
+// WithEncryption the data is encrypted before it is uploaded. With WithDeduplication the data
+// is only uploaded if there is no copy of it yet. With WithWriteStaging the data is served
+// from memory until S3 serves it.
+func (f FileSystem) WriteFile(name string, data []byte, contentType string) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	ctx := context.Background()
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(f.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	}
+
+	plain := data
+	if f.encryption != nil {
+		enc, metadata, err := f.newObjectCipher(ctx)
+		if err != nil {
+			return err
+		}
+		data = enc.encrypt(data)
+		input.Metadata = metadata
+	}
+	var out *s3.PutObjectOutput
+	if f.dedupPrefix != "" {
+		out, err = f.writeDeduplicated(ctx, input, plain, data)
+	} else {
+		input.Body = bytes.NewReader(data)
+		out, err = f.putObject(ctx, input)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.staging.add(key, plain, aws.StringValue(out.ETag), contentType)
+	return nil
+}
+
+// putObject calls PutObject and converts the error with toFSError
+func (f FileSystem) putObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	key := aws.StringValue(input.Key)
+	ctx, span := f.startSpan(ctx, "s3.PutObject", key)
+
+	start := time.Now()
+	out, err := f.s3.PutObjectWithContext(ctx, input)
+	f.requestDone(ctx, "PutObject", start, err, "key", key)
+	f.staging.drop(key)
+	endSpan(span, err)
+	if err != nil {
+		return nil, toFSError(err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,36 @@
+package s3fs
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte(`{"version": 3}`)
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), content, 0644); err != nil {
+		t.Fatalf("error: writing manifest: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "empty.txt"), nil, 0644); err != nil {
+		t.Fatalf("error: writing empty file: %s", err)
+	}
+
+	data, err := ReadFile(http.Dir(dir), "/manifest.json")
+	if err != nil {
+		t.Fatalf("error: reading manifest: %s", err)
+	}
+	if string(data) != string(content) || cap(data) != len(content) {
+		t.Fatalf("error: read %q with capacity %d", data, cap(data))
+	}
+
+	data, err = ReadFile(http.Dir(dir), "/empty.txt")
+	if err != nil || len(data) != 0 {
+		t.Fatalf("error: reading empty file returned %q, %v", data, err)
+	}
+
+	if _, err := ReadFile(http.Dir(dir), "/missing.json"); !os.IsNotExist(err) {
+		t.Fatalf("error: expected a not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// maxDeleteObjects is the maximum number of keys of a DeleteObjects request
+const maxDeleteObjects = 1000
+
+// ErrRemoveRoot is returned by RemoveAll when the name maps to the root of the bucket
+var ErrRemoveRoot = errors.New("s3fs: refusing to remove every object in the bucket")
+
+// DeleteError is a key that S3 failed to delete. See RemoveAll
+type DeleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+func (e *DeleteError) Error() string {
+	return fmt.Sprintf("s3fs: deleting %s: %s: %s", e.Key, e.Code, e.Message)
+}
+
+// Remove removes the object with the name. Like DeleteObject, removing a missing object
+// isn't an error.
+func (f FileSystem) Remove(name string) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	return f.deleteObject(context.Background(), key)
+}
+
+// RemoveAll removes the object with the name and every object under name + "/", like
+// os.RemoveAll removes a directory. Keys are listed with ListObjectsV2 and deleted in
+// DeleteObjects batches of 1000. Keys that fail to delete don't stop the removal; they are
+// returned at the end as *DeleteError joined with errors.Join. A missing name isn't an error.
+// Folder markers, "name/" objects, are removed along with the rest of the directory.
+func (f FileSystem) RemoveAll(name string) error {
+	key, err := f.key(name)
+	if err != nil {
+		return err
+	}
+
+	key = strings.TrimSuffix(key, "/")
+	if key == "" || key == "." {
+		return ErrRemoveRoot
+	}
+
+	ctx := context.Background()
+	errs := []error{}
+	batch := []*s3.ObjectIdentifier{{Key: aws.String(key)}}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(f.bucket),
+		Prefix: aws.String(key + "/"),
+	}
+	for {
+		list, err := f.listObjects(ctx, input)
+		if err != nil {
+			return errors.Join(append(errs, toFSError(err))...)
+		}
+
+		for _, object := range list.Contents {
+			batch = append(batch, &s3.ObjectIdentifier{Key: object.Key})
+			if len(batch) == maxDeleteObjects {
+				errs = append(errs, f.deleteObjects(ctx, batch)...)
+				batch = batch[:0]
+			}
+		}
+
+		if !aws.BoolValue(list.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = list.NextContinuationToken
+	}
+
+	if len(batch) > 0 {
+		errs = append(errs, f.deleteObjects(ctx, batch)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// deleteObjects deletes a batch of at most maxDeleteObjects keys and returns the
+// errors of the keys that couldn't be deleted
+func (f FileSystem) deleteObjects(ctx context.Context, batch []*s3.ObjectIdentifier) []error {
+	start := time.Now()
+	out, err := f.s3.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(f.bucket),
+		Delete: &s3.Delete{
+			Objects: batch,
+			Quiet:   aws.Bool(true),
+		},
+	})
+	f.requestDone(ctx, "DeleteObjects", start, err, "keys", len(batch))
+	for _, object := range batch {
+		f.staging.drop(aws.StringValue(object.Key))
+	}
+	if err != nil {
+		return []error{err}
+	}
+
+	errs := make([]error, 0, len(out.Errors))
+	for _, e := range out.Errors {
+		errs = append(errs, &DeleteError{
+			Key:     aws.StringValue(e.Key),
+			Code:    aws.StringValue(e.Code),
+			Message: aws.StringValue(e.Message),
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,16 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemoveAllRoot(t *testing.T) {
+	s3Fs := New("public-sample-data", "us-east-1", WithKeyMapper(PathKeyMapper))
+
+	for _, name := range []string{"/", "", "/.."} {
+		if err := s3Fs.RemoveAll(name); !errors.Is(err, ErrRemoveRoot) {
+			t.Fatalf("error: expected ErrRemoveRoot removing %q, got %v", name, err)
+		}
+	}
+}
@@ -2,17 +2,24 @@
 package s3fs
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // FileRanges wraps start and end. See FileSystemWithRanges
@@ -22,50 +29,133 @@ type FileRanges struct {
 
 // FileSystem implements http.FileSystem
 type FileSystem struct {
-	s3     *s3.S3
-	bucket string
+	s3        *s3.S3
+	config    *aws.Config
+	bucket    string
+	keyMapper KeyMapper
+
+	indexFile   string
+	spaFallback bool
+
+	// folderMarkers makes Mkdir create zero-byte "name/" objects
+	folderMarkers bool
+	// decompress makes Open decompress gzip-encoded objects
+	decompress bool
+	// encryption wraps the data keys of client-side encrypted objects
+	encryption KeyWrapper
+	// dedupPrefix is where WriteFile stores content by its hash, if it is set
+	dedupPrefix string
+	// progress is called as Files and DownloadTo transfer data
+	progress         ProgressFunc
+	progressInterval time.Duration
+	// hedgeDelay is how long a GetObject waits before it is sent again, see WithHedging
+	hedgeDelay time.Duration
+	// maxObjectSize is the size of the largest object Open accepts, if it is positive
+	maxObjectSize int64
+	// deleteMarkers makes Open and Stat of missing objects look for delete markers
+	deleteMarkers bool
+	// interceptor is called before GetObject, HeadObject and ListObjectsV2 requests
+	interceptor RequestInterceptor
+	// names normalizes names before they are mapped to keys, see WithNameNormalization
+	names *NameOptions
+	// staging keeps the data of recent writes until S3 serves it, see WithWriteStaging
+	staging *writeStaging
+
+	metrics Metrics
+	tracer  trace.Tracer
+	logger  Logger
+
+	verifyChecksums bool
+
+	rateLimit    *tokenBucket
+	requestSlots chan struct{}
+
+	assumeRole *AssumeRole
 }
 
 // FileSystemWithRanges implements http.FileSystem and supports range requests
 // You will need to create a separate FileSystemWithRanges for every request if you are using
 // something like http.FileServer. Each request will need to call Open() for its range specified
-// in FileSystemWithRanges.ranges.
+// in FileSystemWithRanges.ranges. FileServer handles range requests without this.
 type FileSystemWithRanges struct {
-	s3     *s3.S3
-	bucket string
+	fs     FileSystem
 	ranges FileRanges
 }
 
-// File implements http.File
+// File implements http.File. File is seekable: the body of the object is fetched lazily
+// from the current offset after a Seek.
 type File struct {
-	fs   FileSystem
-	body io.ReadCloser
-	stat fileStat
+	fs          FileSystem
+	ctx         context.Context
+	key         string
+	body        io.ReadCloser
+	stat        fileStat
+	offset      int64
+	contentType string
+	etag        string
+	header      http.Header
+	rangeInfo   *RangeInfo
+	checksum    *checksum
+	cipher      *objectCipher
+	progress    *progress
+	dir         *dirReader
+	// failover is the FileSystem that Read continues from if reading fails, see FailoverFS
+	failover *FileSystem
+	// staged is the data of a File opened from WithWriteStaging
+	staged []byte
 }
 
 type fileStat struct {
-	name    string
-	size    int64
-	modTime time.Time
+	name      string
+	size      int64
+	totalSize int64
+	modTime   time.Time
+	isDir     bool
+	// storageClass is empty for STANDARD, which S3 doesn't report
+	storageClass string
 }
 
-// New creates FileSystem and doesn't support ranges.
-func New(bucket, region string) *FileSystem {
-	return &FileSystem{
-		s3: s3.New(session.New(), &aws.Config{
+// New creates FileSystem and doesn't support ranges. bucket is the name of a bucket or the ARN
+// of an access point or Object Lambda access point, whose requests are sent to the region of
+// the ARN rather than region. Object Lambda access points can only be read from.
+func New(bucket, region string, opts ...Option) *FileSystem {
+	f := &FileSystem{
+		config: &aws.Config{
 			Region: aws.String(region),
-		}),
-		bucket: bucket,
+		},
+		bucket:  bucket,
+		metrics: nopMetrics{},
+		tracer:  nopTracer,
+		logger:  nopLogger{},
 	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if _, ok := parseBucketARN(bucket); ok {
+		f.config.S3UseARNRegion = aws.Bool(true)
+	}
+
+	sess := session.New()
+	if f.assumeRole != nil {
+		f.config.Credentials = f.assumeRole.credentials(sess, f.config)
+	}
+
+	f.s3 = s3.New(sess, f.config)
+	f.s3.Handlers.Build.PushBack(identityEncoding)
+	if _, ok := f.logger.(nopLogger); !ok {
+		f.s3.Handlers.AfterRetry.PushFront(f.logRetries)
+	}
+	f.installLimits()
+
+	return f
 }
 
 // NewWithRange creates FileSystemWithRanges with support for ranges
-func NewWithRange(bucket, region string, ranges FileRanges) *FileSystemWithRanges {
+func NewWithRange(bucket, region string, ranges FileRanges, opts ...Option) *FileSystemWithRanges {
 	return &FileSystemWithRanges{
-		s3: s3.New(session.New(), &aws.Config{
-			Region: aws.String(region),
-		}),
-		bucket: bucket,
+		fs:     *New(bucket, region, opts...),
 		ranges: ranges,
 	}
 }
@@ -78,95 +168,335 @@ func NewFileRanges(start, end int64) FileRanges {
 	}
 }
 
-func (f FileSystemWithRanges) getSize(name string) int64 {
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(f.bucket),
-		Key:    aws.String(name),
+// Start returns the offset of the first byte of the range
+func (r FileRanges) Start() int64 {
+	return r.start
+}
+
+// End returns the offset of the last byte of the range, which is included in it
+func (r FileRanges) End() int64 {
+	return r.end
+}
+
+// ErrObjectChanged is returned when an object was overwritten while it was being read or
+// copied, detected by S3 rejecting a request conditional on the ETag seen first
+var ErrObjectChanged = errors.New("s3fs: object changed")
+
+// toFSError converts S3 errors for missing objects to os.ErrNotExist, failed ETag
+// preconditions to ErrObjectChanged and reads of archived objects to ErrArchived, and returns
+// every other error unchanged
+func toFSError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, "NotFound":
+			return os.ErrNotExist
+		case "PreconditionFailed":
+			return ErrObjectChanged
+		case s3.ErrCodeInvalidObjectState:
+			return ErrArchived
+		}
 	}
+	return err
+}
 
-	object, err := f.s3.GetObject(input)
+// getObject calls GetObject and converts the error with toFSError
+func (f FileSystem) getObject(ctx context.Context, input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	key, rng := aws.StringValue(input.Key), aws.StringValue(input.Range)
+	ctx, span := f.startSpan(ctx, "s3.GetObject", key, attribute.String("s3fs.range", rng))
+
+	opts, err := f.intercept(ctx, "GetObject", input)
 	if err != nil {
-		return 0
+		endSpan(span, err)
+		return nil, err
 	}
+	// the interceptor may have changed the range
+	rng = aws.StringValue(input.Range)
 
-	return aws.Int64Value(object.ContentLength)
-}
+	start := time.Now()
+	object, err := f.sendGetObject(ctx, input, opts...)
+	f.requestDone(ctx, "GetObject", start, err, "key", key, "range", rng)
+	if err != nil {
+		endSpan(span, err)
+		return nil, toFSError(err)
+	}
 
-func newFile(stat fileStat, body io.ReadCloser) (*File, error) {
-	return &File{
-		body: body,
-		stat: stat,
-	}, nil
+	object.Body = &objectBody{
+		ReadCloser: countingBody{ReadCloser: object.Body, metrics: f.metrics},
+		ctx:        ctx,
+		span:       span,
+		logger:     f.logger,
+		key:        key,
+		rng:        rng,
+		start:      start,
+	}
+	return object, nil
 }
 
-// Open returns a File with the name of the object
-func (f FileSystem) Open(name string) (http.File, error) {
-	name = filepath.Base(name)
+// headObject calls HeadObject and converts the error with toFSError
+func (f FileSystem) headObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	key := aws.StringValue(input.Key)
+	ctx, span := f.startSpan(ctx, "s3.HeadObject", key)
 
-	input := &s3.GetObjectInput{
-		Bucket: aws.String(f.bucket),
-		Key:    aws.String(name),
+	opts, err := f.intercept(ctx, "HeadObject", input)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
 	}
 
-	object, err := f.s3.GetObject(input)
+	start := time.Now()
+	object, err := f.s3.HeadObjectWithContext(ctx, input, opts...)
+	f.requestDone(ctx, "HeadObject", start, err, "key", key)
+	endSpan(span, err)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case s3.ErrCodeNoSuchKey:
-				return nil, os.ErrNotExist
-			default:
-				return nil, aerr
-			}
-		} else {
-			return nil, aerr
-		}
+		return nil, toFSError(err)
+	}
+
+	return object, nil
+}
+
+// listObjects calls ListObjectsV2
+func (f FileSystem) listObjects(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	prefix := aws.StringValue(input.Prefix)
+	ctx, span := f.startSpan(ctx, "s3.ListObjectsV2", prefix)
+
+	opts, err := f.intercept(ctx, "ListObjectsV2", input)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	list, err := f.s3.ListObjectsV2WithContext(ctx, input, opts...)
+	f.requestDone(ctx, "ListObjectsV2", start, err, "prefix", prefix)
+	if err == nil {
+		span.SetAttributes(attribute.Int("s3fs.keys", len(list.Contents)))
 	}
+	endSpan(span, err)
 
+	return list, err
+}
+
+func newFile(ctx context.Context, fs FileSystem, key string, object *s3.GetObjectOutput) (*File, error) {
 	stat := fileStat{
-		name:    name,
-		size:    aws.Int64Value(object.ContentLength),
-		modTime: aws.TimeValue(object.LastModified),
+		name:         path.Base(key),
+		size:         aws.Int64Value(object.ContentLength),
+		modTime:      aws.TimeValue(object.LastModified),
+		storageClass: aws.StringValue(object.StorageClass),
+	}
+	stat.totalSize = stat.size
+
+	fi := &File{
+		fs:          fs,
+		ctx:         ctx,
+		key:         key,
+		body:        object.Body,
+		stat:        stat,
+		contentType: aws.StringValue(object.ContentType),
+		etag:        aws.StringValue(object.ETag),
+		header:      objectHeader(object),
 	}
 
-	fi, err := newFile(stat, object.Body)
+	if object.ContentRange != nil && fs.encryption != nil && isEncrypted(object.Metadata) {
+		// the range is of the ciphertext, which can't be decrypted without the rest of its chunks
+		object.Body.Close()
+		return nil, ErrEncryptedRange
+	}
+
+	enc, err := fs.openObjectCipher(ctx, object.Metadata, fi.stat.size)
 	if err != nil {
+		object.Body.Close()
 		return nil, err
 	}
 
+	if enc != nil {
+		fi.cipher = enc
+		fi.body = enc.decrypt(object.Body, 0)
+		fi.stat.size = plainSize(fi.stat.size)
+		fi.stat.totalSize = fi.stat.size
+	} else if fs.decompress && aws.StringValue(object.ContentEncoding) == "gzip" && object.ContentRange == nil {
+		body, err := newGzipBody(object.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		fi.body = body
+		fi.stat.size, fi.stat.totalSize = SizeUnknown, SizeUnknown
+		fi.header.Del("Content-Encoding")
+	} else if object.ContentLength == nil {
+		// a custom HTTP client can decompress the body and drop its length
+		fi.stat.size, fi.stat.totalSize = SizeUnknown, SizeUnknown
+	} else if contentRange := aws.StringValue(object.ContentRange); contentRange != "" {
+		info, err := parseContentRange(contentRange)
+		if err != nil {
+			object.Body.Close()
+			return nil, err
+		}
+
+		fi.rangeInfo = &info
+		fi.stat.totalSize = info.TotalSize
+	} else if fs.verifyChecksums {
+		fi.checksum = newChecksum(object)
+	}
+
+	if err := fi.checkSize(); err != nil {
+		fi.body.Close()
+		return nil, err
+	}
+
+	fi.progress = fs.newProgress(fi.stat.size, 0)
+	fs.metrics.ObjectOpened()
+	fs.logger.Log(ctx, slog.LevelDebug, "s3fs: open", "key", key, "size", fi.stat.size,
+		"range", aws.StringValue(object.ContentRange))
 	return fi, nil
 }
 
+// objectHeader returns the headers of object that describe its content to clients
+func objectHeader(object *s3.GetObjectOutput) http.Header {
+	header := http.Header{}
+	set := func(name string, value *string) {
+		if v := aws.StringValue(value); v != "" {
+			header.Set(name, v)
+		}
+	}
+
+	set("Cache-Control", object.CacheControl)
+	set("Content-Disposition", object.ContentDisposition)
+	set("Content-Encoding", object.ContentEncoding)
+	set("Content-Language", object.ContentLanguage)
+	set("Expires", object.Expires)
+	for name, value := range object.Metadata {
+		set("X-Amz-Meta-"+name, value)
+	}
+
+	return header
+}
+
 // Open returns a File with the name of the object
-func (f FileSystemWithRanges) Open(name string) (http.File, error) {
-	name = filepath.Base(name)
+func (f FileSystem) Open(name string) (http.File, error) {
+	return f.OpenContext(context.Background(), name)
+}
+
+// OpenContext is like Open. ctx is used for the GetObject of Open and of every
+// later Read after a Seek.
+func (f FileSystem) OpenContext(ctx context.Context, name string) (http.File, error) {
+	fi, err := f.openFile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fi, nil
+}
+
+func (f FileSystem) openFile(ctx context.Context, name string) (*File, error) {
+	if f.indexFile != "" && (name == "" || strings.HasSuffix(name, "/")) {
+		fi, err := f.openKey(ctx, name+f.indexFile)
+		if !errors.Is(err, os.ErrNotExist) {
+			return fi, err
+		}
+	}
+
+	fi, err := f.openKey(ctx, name)
+	if f.spaFallback && errors.Is(err, os.ErrNotExist) {
+		return f.openKey(ctx, "/"+f.spaEntryPoint())
+	}
+
+	return fi, err
+}
+
+// spaEntryPoint returns the name of the object served by the SPA fallback
+func (f FileSystem) spaEntryPoint() string {
+	if f.indexFile == "" {
+		return "index.html"
+	}
+
+	return f.indexFile
+}
+
+// openKey opens the object with the key for name, or the directory of the key if there is no
+// such object but there are keys under the key followed by a slash
+func (f FileSystem) openKey(ctx context.Context, name string) (*File, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" || strings.HasSuffix(key, "/") {
+		return f.openDir(ctx, key)
+	}
+
+	spanCtx, span := f.startSpan(ctx, "s3fs.Open", key)
 
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(f.bucket),
-		Key:    aws.String(name),
-		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", f.ranges.start, f.ranges.end)),
+		Key:    aws.String(key),
+	}
+	if f.verifyChecksums {
+		input.ChecksumMode = aws.String(s3.ChecksumModeEnabled)
 	}
 
-	object, err := f.s3.GetObject(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case s3.ErrCodeNoSuchKey:
-				return nil, os.ErrNotExist
-			default:
-				return nil, aerr
+	object, err := f.getObject(spanCtx, input)
+	if staged := f.staging.get(key); staged != nil {
+		var etag string
+		if err == nil {
+			etag = aws.StringValue(object.ETag)
+		}
+		if f.staging.stale(key, staged, etag, err) {
+			if err == nil {
+				object.Body.Close()
 			}
-		} else {
-			return nil, aerr
+			span.End()
+			return f.openStaged(ctx, key, staged), nil
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		fi, dirErr := f.openDir(spanCtx, key)
+		if dirErr == nil {
+			fi.ctx = ctx
+			span.End()
+			return fi, nil
 		}
+		if !errors.Is(dirErr, os.ErrNotExist) {
+			err = dirErr
+		} else if f.deleteMarkers {
+			err = f.deletedError(spanCtx, key)
+		}
+	}
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
 	}
 
-	stat := fileStat{
-		name:    name,
-		size:    f.getSize(name),
-		modTime: aws.TimeValue(object.LastModified),
+	// the File keeps ctx rather than spanCtx so that the spans of later reads
+	// aren't children of the finished Open span
+	var fi *File
+	if ref, ok := contentRef(object.Metadata, aws.Int64Value(object.ContentLength)); ok {
+		fi, err = f.openRef(ctx, key, ref, object)
+	} else {
+		fi, err = newFile(ctx, f, key, object)
+	}
+	endSpan(span, err)
+	return fi, err
+}
+
+// Open returns a File with the name of the object
+func (f FileSystemWithRanges) Open(name string) (http.File, error) {
+	key, err := f.fs.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", f.ranges.start, f.ranges.end)),
+	}
+
+	object, err := f.fs.getObject(context.Background(), input)
+	if err != nil {
+		return nil, err
 	}
 
-	fi, err := newFile(stat, object.Body)
+	fi, err := newFile(context.Background(), f.fs, key, object)
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +512,17 @@ func (f fileStat) Size() int64 {
 	return f.size
 }
 
+// TotalSize returns the size of the whole object, which is larger than Size for ranged files.
+// It is -1 if S3 didn't report the size of the object.
+func (f fileStat) TotalSize() int64 {
+	return f.totalSize
+}
+
 func (f fileStat) Mode() os.FileMode {
+	if f.isDir {
+		return os.ModeDir | 0755
+	}
+
 	// owner: read, write, execute
 	// everyone else: only read
 	return os.FileMode(0644)
@@ -193,33 +533,233 @@ func (f fileStat) ModTime() time.Time {
 }
 
 func (f fileStat) IsDir() bool {
-	return false
+	return f.isDir
 }
 
 func (f fileStat) Sys() interface{} {
 	return nil
 }
 
-// Close closes the file
-func (f File) Close() error {
-	return f.body.Close()
+// Close closes the file. With WithChecksumVerification, Close returns ErrChecksumMismatch
+// if the file was read to the end and its checksum doesn't match the one stored by S3.
+func (f *File) Close() error {
+	if f.body == nil {
+		return nil
+	}
+
+	err := f.body.Close()
+	f.body = nil
+
+	if f.checksum != nil && f.checksum.n == f.stat.size {
+		if verr := f.checksum.verify(); verr != nil {
+			f.fs.logger.Log(f.ctx, slog.LevelError, "s3fs: checksum mismatch", "key", f.key, "etag", f.etag)
+			return verr
+		}
+	}
+
+	return err
 }
 
-func (f File) Read(p []byte) (int, error) {
-	return io.ReadFull(f.body, p)
+// Read reads from the current offset. Reads after a Seek fetch the rest of the file with a
+// GetObject conditional on the ETag seen by Open and return ErrObjectChanged if the object
+// was overwritten in the meantime, instead of mixing bytes of two versions.
+//
+// Read fills p unless fewer bytes remain, in which case it reads the rest of the file and
+// returns a nil error; it returns 0, io.EOF at or past the end of the file, including for
+// empty objects, and 0, nil for an empty p. A body shorter than the object fails with
+// io.ErrUnexpectedEOF.
+func (f *File) Read(p []byte) (int, error) {
+	if f.dir != nil {
+		return 0, errIsDir
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if f.stat.size == SizeUnknown {
+		return f.readStream(p)
+	}
+	if f.offset >= f.stat.size {
+		return 0, io.EOF
+	}
+
+	// the body ends at the end of the object or range, so reading up to there
+	// fills p and a shorter body means the response was cut short
+	if remaining := f.stat.size - f.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	if f.body == nil {
+		body, err := f.openRange(f.offset, f.stat.size-f.offset)
+		if err != nil && f.failover != nil && canFailover(f.ctx, err) {
+			f.failOver(err)
+			return f.Read(p)
+		}
+		if err != nil {
+			return 0, err
+		}
+		f.body = body
+	}
+
+	n, err := io.ReadFull(f.body, p)
+	f.progress.add(int64(n))
+	if f.checksum != nil {
+		if f.checksum.n == f.offset {
+			f.checksum.Write(p[:n])
+		} else {
+			// a Seek skipped or repeated bytes, so the checksum can't be computed
+			f.checksum = nil
+		}
+	}
+	f.offset += int64(n)
+
+	if err != nil && f.failover != nil && canFailover(f.ctx, err) {
+		f.failOver(err)
+		m, err := f.Read(p[n:])
+		return n + m, err
+	}
+	return n, err
 }
 
-// Readdir returns an empty []os.FileInfo
-func (f File) Readdir(count int) ([]os.FileInfo, error) {
-	return []os.FileInfo{}, nil
+// openRange returns a body with the n bytes of the File starting at off. If-Match makes sure
+// the bytes come from the same version of the object as the bytes read before.
+func (f *File) openRange(off, n int64) (io.ReadCloser, error) {
+	if f.staged != nil {
+		return io.NopCloser(bytes.NewReader(f.staged[off : off+n])), nil
+	}
+
+	start, end := off, off+n-1
+	if f.cipher != nil {
+		start, end = f.cipher.cipherRange(off, n)
+	}
+	if f.rangeInfo != nil {
+		start += f.rangeInfo.Offset
+		end += f.rangeInfo.Offset
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(f.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+	}
+	if f.etag != "" {
+		input.IfMatch = aws.String(f.etag)
+	}
+
+	object, err := f.fs.getObject(f.ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.cipher != nil {
+		return f.cipher.decrypt(object.Body, off), nil
+	}
+	return object.Body, nil
 }
 
-// Seek is not implemented. Seek needs the entire file to be on disk or memory. See FileSystemWithRanges
-func (f File) Seek(offset int64, whence int) (int64, error) {
-	return 0, nil
+// ReadAt reads len(p) bytes starting at off with a ranged GetObject, independently of
+// Read and Seek, so it can be called concurrently. Like Read after a Seek, it fails with
+// ErrObjectChanged if the object was overwritten since Open.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.dir != nil {
+		return 0, errIsDir
+	}
+	if f.stat.size == SizeUnknown {
+		return 0, ErrNotSeekable
+	}
+	if off < 0 {
+		return 0, errors.New("s3fs: negative offset")
+	}
+	if off >= f.stat.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	n := min(int64(len(p)), f.stat.size-off)
+	body, err := f.openRange(off, n)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	read, err := io.ReadFull(body, p[:n])
+	if err == nil && n < int64(len(p)) {
+		err = io.EOF
+	}
+	return read, err
+}
+
+// Readdir returns the entries of a directory: the objects directly under its prefix and,
+// as directories, the common prefixes one level below. If count > 0, Readdir returns at most
+// count entries and io.EOF once there are no more; otherwise it returns all remaining entries.
+// Entries are listed as they are needed, so Readdir(n) in a loop lists large directories in
+// pages of n keys and can stop early.
+// Readdir returns an empty []os.FileInfo for files that aren't directories.
+func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+	if f.dir == nil {
+		return []os.FileInfo{}, nil
+	}
+
+	return f.dir.readdir(f.ctx, f.fs, count)
 }
 
-// Stat behaves like os.Stat
-func (f File) Stat() (os.FileInfo, error) {
+// Seek sets the offset for the next Read. Seek itself doesn't call S3; the body already
+// being read is discarded and the next Read issues a ranged GetObject from the new offset.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.stat.size == SizeUnknown {
+		// only seeking to the current offset is possible
+		if whence == io.SeekCurrent && offset == 0 {
+			return f.offset, nil
+		}
+		return 0, ErrNotSeekable
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.stat.size
+	default:
+		return 0, errors.New("s3fs: invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("s3fs: negative position")
+	}
+
+	if offset != f.offset && f.body != nil {
+		if err := f.body.Close(); err != nil {
+			f.fs.logger.Log(f.ctx, slog.LevelWarn, "s3fs: closing body on seek", "key", f.key, "error", err)
+		}
+		f.body = nil
+	}
+	f.offset = offset
+
+	return offset, nil
+}
+
+// Stat behaves like os.Stat. Size is the number of bytes that can be read from the File, which
+// for a ranged File is the length of the range, or SizeUnknown for a decompressed File. The
+// size of the whole object is returned by the TotalSize method of the os.FileInfo and by
+// RangeInfo.
+func (f *File) Stat() (os.FileInfo, error) {
 	return f.stat, nil
 }
+
+// ContentType returns the Content-Type of the object as stored in S3
+func (f *File) ContentType() string {
+	return f.contentType
+}
+
+// ETag returns the ETag of the object
+func (f *File) ETag() string {
+	return f.etag
+}
+
+// Header returns the Cache-Control, Content-Disposition, Content-Encoding, Content-Language
+// and Expires headers stored with the object, and its user metadata as X-Amz-Meta-* headers
+func (f *File) Header() http.Header {
+	return f.header.Clone()
+}
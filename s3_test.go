@@ -1,8 +1,16 @@
 package s3fs
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"log"
+	"os"
 	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/shijuleon/s3fs/s3test"
 )
 
 type testCase struct {
@@ -21,28 +29,48 @@ var testCases = []testCase{
 	},
 	testCase{
 		fileName:     "test_dataset_large.json",
-		fileSize:     102,
+		fileSize:     300 << 10,
 		fileReadSize: 2049,
-		description:  "A file of size approx 300MB",
+		description:  "A file of 300KB",
 	},
 	testCase{
 		fileName:     "wikipedia-20150518.bin",
-		fileSize:     1032,
+		fileSize:     20 << 20,
 		fileReadSize: 1024,
-		description:  "A file of size approx 20GB",
+		description:  "A file of 20MB",
 	},
 }
 
+// newTestServer returns an s3test.Server with the objects of testCases in the bucket
+// "public-sample-data"
+func newTestServer(t *testing.T) *s3test.Server {
+	srv := s3test.NewServer()
+	t.Cleanup(srv.Close)
+
+	for _, c := range testCases {
+		srv.PutObject("public-sample-data", c.fileName, bytes.Repeat([]byte("s"), int(c.fileSize)), "")
+	}
+
+	return srv
+}
+
+// newTestFileSystem returns a FileSystem for the bucket of srv
+func newTestFileSystem(srv *s3test.Server, opts ...Option) *FileSystem {
+	opts = append([]Option{WithEndpoint(srv.URL), WithAnonymousCredentials()}, opts...)
+	return New("public-sample-data", "us-east-1", opts...)
+}
+
 func TestNew(t *testing.T) {
 	New("public-sample-data", "us-east-1")
 }
 
 func TestOpen(t *testing.T) {
-	s3Fs := New("public-sample-data", "us-east-1")
+	s3Fs := newTestFileSystem(newTestServer(t))
 	f, err := s3Fs.Open("passengers.txt")
 	if err != nil {
 		log.Fatalf("Error opening passengers.txt: %s", err)
 	}
+	defer f.Close()
 
 	stat, _ := f.Stat()
 	if stat.Size() != 1046 {
@@ -50,7 +78,7 @@ func TestOpen(t *testing.T) {
 	}
 }
 func TestFileOpen(t *testing.T) {
-	s3Fs := New("public-sample-data", "us-east-1")
+	s3Fs := newTestFileSystem(newTestServer(t))
 
 	for i, t := range testCases {
 		log.Printf("%d. %s", i+1, t.description)
@@ -58,6 +86,7 @@ func TestFileOpen(t *testing.T) {
 		if err != nil {
 			log.Fatalf("error: opening %s: %s", t.fileName, err)
 		}
+		defer f.Close()
 
 		p := make([]byte, t.fileReadSize)
 		n, err := f.Read(p)
@@ -72,13 +101,14 @@ func TestFileOpen(t *testing.T) {
 }
 
 func TestFileStat(t *testing.T) {
-	s3Fs := New("public-sample-data", "us-east-1")
+	s3Fs := newTestFileSystem(newTestServer(t))
 
 	for _, t := range testCases {
 		f, err := s3Fs.Open(t.fileName)
 		if err != nil {
 			log.Fatalf("error: opening %s: %s", t.fileName, err)
 		}
+		defer f.Close()
 
 		stat, _ := f.Stat()
 		if stat.Size() != t.fileSize {
@@ -90,3 +120,115 @@ func TestFileStat(t *testing.T) {
 		}
 	}
 }
+
+func TestSeekAndReadDir(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PutObject("public-sample-data", "docs/setup.html", []byte("<h1>Setup</h1>"), "text/html")
+	srv.PutObject("public-sample-data", "docs/images/logo.png", []byte("png"), "image/png")
+	s3Fs := newTestFileSystem(srv, WithKeyMapper(PathKeyMapper))
+
+	f, err := s3Fs.Open("/docs/setup.html")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(4, io.SeekStart); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	p := make([]byte, 5)
+	if _, err := io.ReadFull(f, p); err != nil || string(p) != "Setup" {
+		t.Fatalf("error: read %q after Seek, %v", p, err)
+	}
+
+	dir, err := s3Fs.Open("/docs")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "images" || !entries[0].IsDir() || entries[1].Name() != "setup.html" {
+		t.Fatalf("error: unexpected entries %v", entries)
+	}
+
+	if _, err := s3Fs.Open("/missing.html"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestReadEdgeCases(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PutObject("public-sample-data", "empty.txt", nil, "text/plain")
+	srv.PutObject("public-sample-data", "small.txt", []byte("hello"), "text/plain")
+	s3Fs := newTestFileSystem(srv)
+
+	empty, err := s3Fs.Open("empty.txt")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer empty.Close()
+
+	if n, err := empty.Read(make([]byte, 16)); n != 0 || err != io.EOF {
+		t.Fatalf("error: expected 0, io.EOF reading an empty object, got %d, %v", n, err)
+	}
+	if data, err := ReadFile(s3Fs, "empty.txt"); err != nil || len(data) != 0 {
+		t.Fatalf("error: expected no data reading an empty object, got %q, %v", data, err)
+	}
+
+	small, err := s3Fs.Open("small.txt")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer small.Close()
+
+	if n, err := small.Read(nil); n != 0 || err != nil {
+		t.Fatalf("error: expected 0, nil for an empty buffer, got %d, %v", n, err)
+	}
+
+	p := make([]byte, 16)
+	if n, err := small.Read(p); n != 5 || err != nil || string(p[:n]) != "hello" {
+		t.Fatalf("error: expected the whole object and a nil error, got %q, %v", p[:n], err)
+	}
+	if n, err := small.Read(p); n != 0 || err != io.EOF {
+		t.Fatalf("error: expected 0, io.EOF at the end, got %d, %v", n, err)
+	}
+
+	if _, err := small.Seek(100, io.SeekStart); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if n, err := small.Read(p); n != 0 || err != io.EOF {
+		t.Fatalf("error: expected 0, io.EOF past the end, got %d, %v", n, err)
+	}
+
+	if _, err := small.Seek(1, io.SeekStart); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, err := io.ReadAll(small); err != nil || string(data) != "ello" {
+		t.Fatalf("error: expected io.ReadAll to read the rest, got %q, %v", data, err)
+	}
+}
+
+func TestToFSError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil), os.ErrNotExist},
+		{awserr.New("NotFound", "Not Found", nil), os.ErrNotExist},
+		{awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil), ErrObjectChanged},
+		{awserr.New(s3.ErrCodeInvalidObjectState, "The operation is not valid for the object's storage class", nil), ErrArchived},
+	}
+
+	for _, c := range cases {
+		if got := toFSError(c.err); got != c.want {
+			t.Fatalf("error: %v converted to %v, want %v", c.err, got, c.want)
+		}
+	}
+
+	other := awserr.New("SlowDown", "Please reduce your request rate.", nil)
+	if got := toFSError(other); got != other {
+		t.Fatalf("error: %v converted to %v", other, got)
+	}
+}
@@ -0,0 +1,101 @@
+// Package s3fsprom provides s3fs.Metrics backed by Prometheus collectors
+package s3fsprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements s3fs.Metrics and prometheus.Collector. Register it with a
+// prometheus.Registerer and pass it to s3fs.WithMetrics.
+type Metrics struct {
+	opens      prometheus.Counter
+	bytes      prometheus.Counter
+	requests   *prometheus.HistogramVec
+	errors     *prometheus.CounterVec
+	cache      *prometheus.CounterVec
+	collectors []prometheus.Collector
+}
+
+// NewMetrics creates Metrics with the metric names prefixed by namespace, for example
+// "myapp" gives myapp_s3fs_downloaded_bytes_total
+func NewMetrics(namespace string) *Metrics {
+	m := &Metrics{
+		opens: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "s3fs",
+			Name:      "objects_opened_total",
+			Help:      "Number of S3 objects opened.",
+		}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "s3fs",
+			Name:      "downloaded_bytes_total",
+			Help:      "Number of bytes read from S3 object bodies.",
+		}),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "s3fs",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of S3 requests by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "s3fs",
+			Name:      "request_errors_total",
+			Help:      "Number of failed S3 requests by operation and S3 error code.",
+		}, []string{"operation", "code"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "s3fs",
+			Name:      "cache_lookups_total",
+			Help:      "Number of lookups in the cache of compressed variants by result, hit or miss.",
+		}, []string{"result"}),
+	}
+	m.collectors = []prometheus.Collector{m.opens, m.bytes, m.requests, m.errors, m.cache}
+
+	return m
+}
+
+// ObjectOpened implements s3fs.Metrics
+func (m *Metrics) ObjectOpened() {
+	m.opens.Inc()
+}
+
+// BytesDownloaded implements s3fs.Metrics
+func (m *Metrics) BytesDownloaded(n int64) {
+	m.bytes.Add(float64(n))
+}
+
+// RequestDone implements s3fs.Metrics
+func (m *Metrics) RequestDone(op string, latency time.Duration, errCode string) {
+	m.requests.WithLabelValues(op).Observe(latency.Seconds())
+	if errCode != "" {
+		m.errors.WithLabelValues(op, errCode).Inc()
+	}
+}
+
+// CacheLookup implements s3fs.Metrics
+func (m *Metrics) CacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cache.WithLabelValues(result).Inc()
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors {
+		c.Collect(ch)
+	}
+}
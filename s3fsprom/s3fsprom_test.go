@@ -0,0 +1,63 @@
+package s3fsprom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// values returns the values of the gathered metrics by name and label values, sorted by
+// label name
+func values(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range metric.GetLabel() {
+				name += " " + label.GetValue()
+			}
+			switch {
+			case metric.Counter != nil:
+				values[name] = metric.GetCounter().GetValue()
+			case metric.Histogram != nil:
+				values[name] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics("test")
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m)
+
+	m.ObjectOpened()
+	m.BytesDownloaded(1024)
+	m.RequestDone("GetObject", 10*time.Millisecond, "")
+	m.RequestDone("GetObject", 10*time.Millisecond, "NoSuchKey")
+	m.CacheLookup(false)
+	m.CacheLookup(true)
+	m.CacheLookup(true)
+
+	want := map[string]float64{
+		"test_s3fs_objects_opened_total":                     1,
+		"test_s3fs_downloaded_bytes_total":                   1024,
+		"test_s3fs_request_duration_seconds GetObject":       2,
+		"test_s3fs_request_errors_total NoSuchKey GetObject": 1,
+		"test_s3fs_cache_lookups_total hit":                  2,
+		"test_s3fs_cache_lookups_total miss":                 1,
+	}
+	got := values(t, reg)
+	for name, value := range want {
+		if got[name] != value {
+			t.Fatalf("error: expected %s to be %v, got %v", name, value, got[name])
+		}
+	}
+}
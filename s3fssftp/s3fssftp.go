@@ -0,0 +1,274 @@
+// Package s3fssftp implements the request server handlers of github.com/pkg/sftp with an
+// s3fs.FileSystem, for an SFTP endpoint serving the objects of a bucket:
+//
+//	fsys := s3fs.New("bucket", "us-east-1", s3fs.WithKeyMapper(s3fs.PathKeyMapper), s3fs.WithFolderMarkers())
+//	server := sftp.NewRequestServer(channel, s3fssftp.Handlers(fsys))
+//
+// The FileSystem must map names to whole keys, like s3fs.PathKeyMapper does. Downloads are
+// ranged reads of s3fs.File and uploads are multipart uploads written with
+// s3fs.FileSystem.Create, or OpenAppend for files opened for appending. Objects can't be
+// changed in place, so uploads must write their data in order. Renames don't replace an
+// existing file, except with the posix-rename@openssh.com extension.
+package s3fssftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"github.com/shijuleon/s3fs"
+)
+
+// ErrNotSequential is returned when an upload writes at an offset it already wrote
+var ErrNotSequential = errors.New("s3fssftp: writes must be sequential")
+
+// maxPendingWrite is the size of the data written past the current end of an upload kept in
+// memory, since SFTP clients send many writes concurrently and they can arrive out of order
+const maxPendingWrite = 64 << 20
+
+// Handlers returns the sftp.Handlers serving fsys
+func Handlers(fsys *s3fs.FileSystem) sftp.Handlers {
+	h := &handler{fs: fsys}
+	return sftp.Handlers{FileGet: h, FilePut: h, FileCmd: h, FileList: h}
+}
+
+type handler struct {
+	fs *s3fs.FileSystem
+}
+
+var _ sftp.PosixRenameFileCmder = (*handler)(nil)
+
+func (h *handler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	file, err := h.fs.OpenContext(r.Context(), r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readerAt{file: file}, nil
+}
+
+func (h *handler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	var w *s3fs.AppendWriter
+	var offset int64
+	var err error
+
+	if r.Pflags().Append {
+		if info, statErr := h.fs.StatContext(r.Context(), r.Filepath); statErr == nil {
+			offset = info.Size()
+		}
+		w, err = h.fs.OpenAppend(r.Filepath)
+	} else {
+		w, err = h.fs.Create(r.Filepath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &writerAt{w: w, offset: offset, pending: map[int64][]byte{}}, nil
+}
+
+func (h *handler) Filecmd(r *sftp.Request) error {
+	switch r.Method {
+	case "Setstat":
+		// S3 doesn't store modes, owners and times
+		return nil
+	case "Rename":
+		// unlike rename(2), SFTP renames don't replace an existing file
+		if _, err := h.fs.StatContext(r.Context(), r.Target); err == nil {
+			return os.ErrExist
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return h.rename(r)
+	case "Rmdir":
+		dir, err := h.fs.OpenContext(r.Context(), r.Filepath)
+		if err != nil {
+			return err
+		}
+		entries, err := dir.Readdir(1)
+		dir.Close()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("s3fssftp: directory %s not empty", r.Filepath)
+		}
+		// removes the folder marker, if any
+		return h.fs.RemoveAll(r.Filepath)
+	case "Mkdir":
+		return h.fs.Mkdir(r.Filepath)
+	case "Remove":
+		return h.fs.Remove(r.Filepath)
+	}
+
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// PosixRename handles the posix-rename@openssh.com extension, which replaces the target
+func (h *handler) PosixRename(r *sftp.Request) error {
+	return h.rename(r)
+}
+
+// rename moves the file of the request to its target. Directories can't be renamed, since
+// that would mean moving every object under them.
+func (h *handler) rename(r *sftp.Request) error {
+	info, err := h.fs.StatContext(r.Context(), r.Filepath)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return sftp.ErrSSHFxOpUnsupported
+	}
+	return h.fs.Move(r.Filepath, r.Target)
+}
+
+func (h *handler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		dir, err := h.fs.OpenContext(r.Context(), r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		defer dir.Close()
+
+		entries, err := dir.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt(entries), nil
+	case "Stat":
+		info, err := h.fs.StatContext(r.Context(), r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// readerAt streams the body of the File for reads continuing where the previous one ended
+// and uses a ranged ReadAt for the others, so that out of order reads don't reopen the body
+type readerAt struct {
+	mu     sync.Mutex
+	file   http.File
+	offset int64
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if off != r.offset {
+		if ra, ok := r.file.(io.ReaderAt); ok {
+			return ra.ReadAt(p, off)
+		}
+		if _, err := r.file.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+		r.offset = off
+	}
+
+	n, err := io.ReadFull(r.file, p)
+	r.offset += int64(n)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *readerAt) Close() error {
+	return r.file.Close()
+}
+
+// writerAt writes to an AppendWriter in order, holding writes past the current end
+// until the data before them has been written
+type writerAt struct {
+	mu      sync.Mutex
+	w       *s3fs.AppendWriter
+	offset  int64
+	pending map[int64][]byte
+	size    int64
+	err     error
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	switch {
+	case off < w.offset:
+		w.err = ErrNotSequential
+		return 0, w.err
+	case off > w.offset:
+		if w.size+int64(len(p)) > maxPendingWrite {
+			w.err = ErrNotSequential
+			return 0, w.err
+		}
+		w.pending[off] = append([]byte(nil), p...)
+		w.size += int64(len(p))
+		return len(p), nil
+	}
+
+	if err := w.write(p); err != nil {
+		return 0, err
+	}
+
+	for {
+		next, ok := w.pending[w.offset]
+		if !ok {
+			return len(p), nil
+		}
+		delete(w.pending, w.offset)
+		w.size -= int64(len(next))
+		if err := w.write(next); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (w *writerAt) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	w.err = err
+	return err
+}
+
+// Close completes the upload, or aborts it if a write failed or left a gap
+func (w *writerAt) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil && len(w.pending) > 0 {
+		w.err = ErrNotSequential
+	}
+	if w.err != nil {
+		w.w.Abort()
+		return w.err
+	}
+
+	return w.w.Close()
+}
+
+// listerAt lists a slice of FileInfo
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(entries []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := copy(entries, l[offset:])
+	if n < len(entries) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,204 @@
+package s3fssftp
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/shijuleon/s3fs"
+	"github.com/shijuleon/s3fs/s3test"
+)
+
+// the SSH_FXF_WRITE and SSH_FXF_APPEND flags of SFTP open requests
+const (
+	sshFxfWrite  = 0x02
+	sshFxfAppend = 0x04
+)
+
+// newTestHandler returns the handler of the bucket "site" of an s3test.Server
+func newTestHandler(t *testing.T) (*handler, *s3test.Server) {
+	srv := s3test.NewServer()
+	t.Cleanup(srv.Close)
+	srv.PutObject("site", "docs/setup.html", []byte("<h1>Setup</h1>"), "text/html")
+
+	fsys := s3fs.New("site", "us-east-1", s3fs.WithEndpoint(srv.URL), s3fs.WithAnonymousCredentials(),
+		s3fs.WithKeyMapper(s3fs.PathKeyMapper), s3fs.WithFolderMarkers())
+	return &handler{fs: fsys}, srv
+}
+
+// upload opens the file for writing with flags and writes the chunks at the offsets, in the order given
+func upload(t *testing.T, h *handler, name string, flags uint32, offsets []int64, chunks ...string) error {
+	r := sftp.NewRequest("Put", name)
+	r.Flags = flags
+	w, err := h.Filewrite(r)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	for i, chunk := range chunks {
+		if _, err := w.WriteAt([]byte(chunk), offsets[i]); err != nil {
+			w.(io.Closer).Close()
+			return err
+		}
+	}
+	return w.(io.Closer).Close()
+}
+
+func TestWriteAtOutOfOrder(t *testing.T) {
+	h, srv := newTestHandler(t)
+
+	if err := upload(t, h, "/docs/notes.txt", sshFxfWrite, []int64{6, 11, 0}, "world", "!", "hello "); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/notes.txt"); string(data) != "hello world!" {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	if err := upload(t, h, "/docs/gap.txt", sshFxfWrite, []int64{0, 10}, "start", "end"); !errors.Is(err, ErrNotSequential) {
+		t.Fatalf("error: expected ErrNotSequential for a gap, got %v", err)
+	}
+	if err := upload(t, h, "/docs/rewrite.txt", sshFxfWrite, []int64{0, 2}, "abcd", "x"); !errors.Is(err, ErrNotSequential) {
+		t.Fatalf("error: expected ErrNotSequential writing twice at an offset, got %v", err)
+	}
+	for _, key := range []string{"docs/gap.txt", "docs/rewrite.txt"} {
+		if _, ok := srv.Object("site", key); ok {
+			t.Fatalf("error: expected the failed upload of %s to be aborted", key)
+		}
+	}
+
+	if err := upload(t, h, "/docs/notes.txt", sshFxfWrite|sshFxfAppend, []int64{12}, " again"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/notes.txt"); string(data) != "hello world! again" {
+		t.Fatalf("error: stored %q", data)
+	}
+}
+
+func TestFileread(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	ra, err := h.Fileread(sftp.NewRequest("Get", "/docs/setup.html"))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer ra.(io.Closer).Close()
+
+	p := make([]byte, 5)
+	if n, err := ra.ReadAt(p, 4); err != nil || string(p[:n]) != "Setup" {
+		t.Fatalf("error: read %q, %v", p[:n], err)
+	}
+	if n, err := ra.ReadAt(p, 0); err != nil || string(p[:n]) != "<h1>S" {
+		t.Fatalf("error: read %q, %v", p[:n], err)
+	}
+	if n, err := ra.ReadAt(p, 12); err != io.EOF || string(p[:n]) != "1>" {
+		t.Fatalf("error: expected io.EOF after the last bytes, read %q, %v", p[:n], err)
+	}
+}
+
+func TestFilelistPaging(t *testing.T) {
+	h, srv := newTestHandler(t)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		srv.PutObject("site", "files/"+name, []byte(name), "text/plain")
+	}
+
+	lister, err := h.Filelist(sftp.NewRequest("List", "/files"))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var names []string
+	page := make([]os.FileInfo, 2)
+	for offset := int64(0); ; {
+		n, err := lister.ListAt(page, offset)
+		for _, info := range page[:n] {
+			names = append(names, info.Name())
+		}
+		offset += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+	}
+	if len(names) != 5 || names[0] != "a.txt" || names[4] != "e.txt" {
+		t.Fatalf("error: listed %v", names)
+	}
+	if n, err := lister.ListAt(page, 5); n != 0 || err != io.EOF {
+		t.Fatalf("error: expected io.EOF past the end, got %d, %v", n, err)
+	}
+
+	lister, err = h.Filelist(sftp.NewRequest("Stat", "/files/c.txt"))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if n, _ := lister.ListAt(page, 0); n != 1 || page[0].Size() != 5 {
+		t.Fatalf("error: unexpected stat %v", page[:n])
+	}
+	if _, err := h.Filelist(sftp.NewRequest("Stat", "/files/missing.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	h, srv := newTestHandler(t)
+	srv.PutObject("site", "docs/install.html", []byte("<h1>Install</h1>"), "text/html")
+
+	rename := func(method, from, to string) error {
+		r := sftp.NewRequest(method, from)
+		r.Target = to
+		if method == "PosixRename" {
+			return h.PosixRename(r)
+		}
+		return h.Filecmd(r)
+	}
+
+	if err := rename("Rename", "/docs/setup.html", "/docs/install.html"); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("error: expected os.ErrExist renaming onto an existing file, got %v", err)
+	}
+	if err := rename("Rename", "/docs/setup.html", "/docs/start.html"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, ok := srv.Object("site", "docs/setup.html"); ok {
+		t.Fatalf("error: expected Rename to remove the old object")
+	}
+
+	if err := rename("PosixRename", "/docs/start.html", "/docs/install.html"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/install.html"); string(data) != "<h1>Setup</h1>" {
+		t.Fatalf("error: expected PosixRename to replace the target, stored %q", data)
+	}
+
+	if err := rename("Rename", "/docs", "/manual"); err != sftp.ErrSSHFxOpUnsupported {
+		t.Fatalf("error: expected renaming a directory to be unsupported, got %v", err)
+	}
+	if err := rename("Rename", "/docs/missing.html", "/docs/other.html"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestFilecmdDirs(t *testing.T) {
+	h, srv := newTestHandler(t)
+
+	if err := h.Filecmd(sftp.NewRequest("Mkdir", "/empty")); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, ok := srv.Object("site", "empty/"); !ok {
+		t.Fatalf("error: expected a folder marker")
+	}
+	if err := h.Filecmd(sftp.NewRequest("Rmdir", "/docs")); err == nil {
+		t.Fatalf("error: expected Rmdir of a directory with files to fail")
+	}
+	if err := h.Filecmd(sftp.NewRequest("Rmdir", "/empty")); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := h.Filecmd(sftp.NewRequest("Remove", "/docs/setup.html")); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if keys := srv.Keys("site"); len(keys) != 0 {
+		t.Fatalf("error: expected no objects left, got %v", keys)
+	}
+}
@@ -0,0 +1,239 @@
+// Package s3fswebdav implements golang.org/x/net/webdav.FileSystem with an s3fs.FileSystem,
+// so that WebDAV clients can browse and edit the objects of a bucket:
+//
+//	fsys := s3fs.New("bucket", "us-east-1", s3fs.WithKeyMapper(s3fs.PathKeyMapper), s3fs.WithFolderMarkers())
+//	http.Handle("/", &webdav.Handler{
+//		FileSystem: s3fswebdav.New(fsys),
+//		LockSystem: webdav.NewMemLS(),
+//	})
+//
+// The FileSystem must map names to whole keys, like s3fs.PathKeyMapper does. Files opened for
+// writing are buffered in memory and stored with WriteFile when they are closed, directories
+// are created as folder markers, and renames are copies followed by deletes.
+package s3fswebdav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/shijuleon/s3fs"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem is a webdav.FileSystem backed by an s3fs.FileSystem
+type FileSystem struct {
+	fs *s3fs.FileSystem
+}
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
+
+// New creates a FileSystem for fsys
+func New(fsys *s3fs.FileSystem) *FileSystem {
+	return &FileSystem{fs: fsys}
+}
+
+// Mkdir creates the directory with s3fs.FileSystem.Mkdir. It fails with os.ErrExist if
+// there already is an object or a directory with the name.
+func (f *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if _, err := f.fs.StatContext(ctx, name); err == nil {
+		return os.ErrExist
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return f.fs.Mkdir(name)
+}
+
+// OpenFile opens the object or directory with the name. With os.O_WRONLY or os.O_RDWR the
+// returned file is buffered in memory, starting with the content of the object unless flag
+// has os.O_TRUNC, and is stored when closed. With os.O_APPEND every write goes to the end.
+func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		file, err := f.fs.OpenContext(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return readOnlyFile{file}, nil
+	}
+
+	info, err := f.fs.StatContext(ctx, name)
+	switch {
+	case err == nil && info.IsDir():
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	case err == nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, os.ErrExist
+	case errors.Is(err, os.ErrNotExist) && flag&os.O_CREATE == 0:
+		return nil, err
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	w := &writeFile{fs: f.fs, name: name, modTime: time.Now()}
+	if err == nil && flag&os.O_TRUNC == 0 {
+		data, err := s3fs.ReadFile(f.fs, name)
+		if err != nil {
+			return nil, err
+		}
+		w.data = data
+	}
+	w.append = flag&os.O_APPEND != 0
+
+	return w, nil
+}
+
+// RemoveAll removes the object with the name and everything under it
+func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	return f.fs.RemoveAll(name)
+}
+
+// Rename moves the object oldName to newName, or every object under the directory oldName
+func (f *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	info, err := f.fs.StatContext(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return f.fs.Move(oldName, newName)
+	}
+
+	if err := f.moveDir(ctx, oldName, newName); err != nil {
+		return err
+	}
+
+	// removes the folder marker of the old directory, if any
+	return f.fs.RemoveAll(oldName)
+}
+
+// moveDir moves the objects of the directory oldName and of its subdirectories to newName
+func (f *FileSystem) moveDir(ctx context.Context, oldName, newName string) error {
+	dir, err := f.fs.OpenContext(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	entries, err := dir.Readdir(-1)
+	dir.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := f.fs.Mkdir(newName); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		oldPath, newPath := path.Join(oldName, entry.Name()), path.Join(newName, entry.Name())
+		if entry.IsDir() {
+			err = f.moveDir(ctx, oldPath, newPath)
+		} else {
+			err = f.fs.Move(oldPath, newPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stat returns the FileInfo of the object or directory with the name
+func (f *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return f.fs.StatContext(ctx, name)
+}
+
+// readOnlyFile is a file opened for reading
+type readOnlyFile struct {
+	http.File
+}
+
+func (readOnlyFile) Write([]byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// writeFile is a file opened for writing, buffered in memory until it is closed
+type writeFile struct {
+	fs      *s3fs.FileSystem
+	name    string
+	data    []byte
+	offset  int64
+	append  bool
+	modTime time.Time
+	closed  bool
+}
+
+func (w *writeFile) Read(p []byte) (int, error) {
+	if w.offset >= int64(len(w.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, w.data[w.offset:])
+	w.offset += int64(n)
+	return n, nil
+}
+
+func (w *writeFile) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if w.append {
+		w.offset = int64(len(w.data))
+	}
+	if end := w.offset + int64(len(p)); end > int64(len(w.data)) {
+		w.data = append(w.data, make([]byte, end-int64(len(w.data)))...)
+	}
+	n := copy(w.data[w.offset:], p)
+	w.offset += int64(n)
+	w.modTime = time.Now()
+	return n, nil
+}
+
+func (w *writeFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += w.offset
+	case io.SeekEnd:
+		offset += int64(len(w.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("s3fswebdav: negative position")
+	}
+
+	w.offset = offset
+	return offset, nil
+}
+
+func (w *writeFile) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+
+	return w.fs.WriteFile(w.name, w.data, "")
+}
+
+func (w *writeFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: w.name, Err: errors.New("not a directory")}
+}
+
+func (w *writeFile) Stat() (os.FileInfo, error) {
+	return writeFileInfo{w}, nil
+}
+
+// writeFileInfo describes the buffered content of a writeFile
+type writeFileInfo struct {
+	w *writeFile
+}
+
+func (i writeFileInfo) Name() string       { return path.Base(strings.TrimSuffix(i.w.name, "/")) }
+func (i writeFileInfo) Size() int64        { return int64(len(i.w.data)) }
+func (i writeFileInfo) Mode() os.FileMode  { return 0644 }
+func (i writeFileInfo) ModTime() time.Time { return i.w.modTime }
+func (i writeFileInfo) IsDir() bool        { return false }
+func (i writeFileInfo) Sys() any           { return nil }
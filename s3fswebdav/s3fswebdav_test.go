@@ -0,0 +1,168 @@
+package s3fswebdav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/shijuleon/s3fs"
+	"github.com/shijuleon/s3fs/s3test"
+)
+
+// newTestFileSystem returns a FileSystem for the bucket "site" of an s3test.Server
+func newTestFileSystem(t *testing.T) (*FileSystem, *s3test.Server) {
+	srv := s3test.NewServer()
+	t.Cleanup(srv.Close)
+	srv.PutObject("site", "docs/setup.html", []byte("<h1>Setup</h1>"), "text/html")
+
+	fsys := s3fs.New("site", "us-east-1", s3fs.WithEndpoint(srv.URL), s3fs.WithAnonymousCredentials(),
+		s3fs.WithKeyMapper(s3fs.PathKeyMapper), s3fs.WithFolderMarkers())
+	return New(fsys), srv
+}
+
+// writeString opens the file with flag, writes s and closes it
+func writeString(f *FileSystem, name string, flag int, s string) error {
+	file, err := f.OpenFile(context.Background(), name, flag, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(file, s); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func TestOpenFileFlags(t *testing.T) {
+	f, srv := newTestFileSystem(t)
+	ctx := context.Background()
+
+	if err := writeString(f, "/docs/setup.html", os.O_WRONLY|os.O_CREATE|os.O_EXCL, "x"); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("error: expected os.ErrExist with O_EXCL, got %v", err)
+	}
+	if err := writeString(f, "/docs/new.html", os.O_WRONLY|os.O_CREATE|os.O_EXCL, "new"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := writeString(f, "/docs/missing.html", os.O_WRONLY, "x"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist without O_CREATE, got %v", err)
+	}
+	if _, err := f.OpenFile(ctx, "/docs", os.O_WRONLY, 0644); err == nil {
+		t.Fatalf("error: expected opening a directory for writing to fail")
+	}
+
+	// without O_TRUNC, writes replace the start of the object
+	if err := writeString(f, "/docs/setup.html", os.O_WRONLY, "<h2>"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/setup.html"); string(data) != "<h2>Setup</h1>" {
+		t.Fatalf("error: stored %q", data)
+	}
+	if err := writeString(f, "/docs/setup.html", os.O_WRONLY|os.O_TRUNC, "<h1>Install</h1>"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/setup.html"); string(data) != "<h1>Install</h1>" {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	file, err := f.OpenFile(ctx, "/docs/new.html", os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	// writes go to the end even after seeking
+	file.Seek(0, io.SeekStart)
+	io.WriteString(file, " page")
+	if err := file.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/new.html"); string(data) != "new page" {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	file, _ = f.OpenFile(ctx, "/docs/new.html", os.O_RDONLY, 0)
+	defer file.Close()
+	if _, err := file.Write([]byte("x")); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("error: expected os.ErrPermission writing a file open for reading, got %v", err)
+	}
+}
+
+func TestWriteFileSeek(t *testing.T) {
+	f, srv := newTestFileSystem(t)
+	ctx := context.Background()
+
+	// seeking past the end doesn't change the size until something is written there
+	file, _ := f.OpenFile(ctx, "/docs/setup.html", os.O_RDWR, 0644)
+	if _, err := file.Seek(100, io.SeekEnd); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if n, err := file.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Fatalf("error: expected io.EOF past the end, got %d, %v", n, err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/setup.html"); string(data) != "<h1>Setup</h1>" {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	file, _ = f.OpenFile(ctx, "/docs/sparse.bin", os.O_WRONLY|os.O_CREATE, 0644)
+	file.Seek(4, io.SeekStart)
+	io.WriteString(file, "end")
+	if info, _ := file.Stat(); info.Size() != 7 || info.Name() != "sparse.bin" {
+		t.Fatalf("error: unexpected size %d of %s", info.Size(), info.Name())
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, _ := srv.Object("site", "docs/sparse.bin"); !bytes.Equal(data, []byte("\x00\x00\x00\x00end")) {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	if _, err := file.Seek(-1, io.SeekStart); err == nil {
+		t.Fatalf("error: expected seeking to a negative position to fail")
+	}
+	if _, err := file.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("error: expected os.ErrClosed, got %v", err)
+	}
+	if err := file.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("error: expected os.ErrClosed closing twice, got %v", err)
+	}
+}
+
+func TestRenameDir(t *testing.T) {
+	f, srv := newTestFileSystem(t)
+	ctx := context.Background()
+
+	if err := f.Mkdir(ctx, "/docs/img", 0755); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := writeString(f, "/docs/img/logo.png", os.O_WRONLY|os.O_CREATE, "png"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := f.Mkdir(ctx, "/docs/img", 0755); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("error: expected os.ErrExist, got %v", err)
+	}
+
+	if err := f.Rename(ctx, "/docs", "/manual"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	want := []string{"manual/", "manual/img/", "manual/img/logo.png", "manual/setup.html"}
+	if keys := srv.Keys("site"); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("error: expected %v after the rename, got %v", want, keys)
+	}
+	if data, _ := srv.Object("site", "manual/img/logo.png"); string(data) != "png" {
+		t.Fatalf("error: stored %q", data)
+	}
+
+	if err := f.RemoveAll(ctx, "/manual"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if keys := srv.Keys("site"); len(keys) != 0 {
+		t.Fatalf("error: expected RemoveAll to remove everything, left %v", keys)
+	}
+	if _, err := f.Stat(ctx, "/manual"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error: expected os.ErrNotExist, got %v", err)
+	}
+}
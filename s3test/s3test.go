@@ -0,0 +1,916 @@
+// Package s3test provides an in-memory S3 server for tests, so that code using s3fs can be
+// tested without AWS credentials or network access:
+//
+//	srv := s3test.NewServer()
+//	defer srv.Close()
+//	srv.PutObject("bucket", "docs/setup.html", []byte("<h1>Setup</h1>"), "text/html")
+//
+//	fsys := s3fs.New("bucket", "us-east-1", s3fs.WithEndpoint(srv.URL), s3fs.WithAnonymousCredentials())
+//
+// The server supports the path-style API used with s3fs.WithEndpoint for GetObject with ranges
+// and If-Match/If-None-Match, HeadObject, PutObject, CopyObject, DeleteObject, DeleteObjects,
+// HeadBucket, CreateBucket, ListObjectsV2 with prefixes, delimiters, StartAfter and
+// continuation tokens, GetObjectTagging and PutObjectTagging, and multipart uploads with
+// UploadPartCopy, whose parts but the last must have 5MB like in S3. Buckets made versioned
+// with EnableVersioning keep the versions of their objects, get delete markers from
+// DeleteObject, and support DeleteObject of a version and ListObjectVersions. Requests aren't
+// authenticated.
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory S3 server
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*object
+	uploads map[string]*upload
+	nextID  int
+	// versions are the versions of the keys of versioned buckets, the latest first
+	versions map[string]map[string][]*version
+}
+
+// version is a version of an object, or a delete marker if object is nil
+type version struct {
+	id           string
+	object       *object
+	lastModified time.Time
+}
+
+type object struct {
+	data         []byte
+	contentType  string
+	etag         string
+	lastModified time.Time
+	metadata     map[string]string
+	header       http.Header
+	tags         []tag
+	versionID    string
+}
+
+type tag struct {
+	Key   string
+	Value string
+}
+
+// upload is a multipart upload in progress
+type upload struct {
+	bucket string
+	key    string
+	header http.Header
+	parts  map[int][]byte
+}
+
+// storedHeaders are the headers of PutObject stored with an object and returned by GetObject
+var storedHeaders = []string{"Cache-Control", "Content-Disposition", "Content-Encoding", "Content-Language", "Expires"}
+
+// NewServer starts a Server. Close it when the test is done.
+func NewServer() *Server {
+	s := &Server{
+		buckets:  map[string]map[string]*object{},
+		uploads:  map[string]*upload{},
+		versions: map[string]map[string][]*version{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close shuts down the server. Unlike httptest.Server.Close, it doesn't wait for the
+// bodies of objects that a test opened but didn't read or close.
+func (s *Server) Close() {
+	s.Server.CloseClientConnections()
+	s.Server.Close()
+}
+
+// CreateBucket creates an empty bucket, unless it already exists
+func (s *Server) CreateBucket(bucket string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = map[string]*object{}
+	}
+}
+
+// EnableVersioning makes a bucket versioned, creating it if needed. Its existing objects
+// become versions with the ID "null", like they do in S3.
+func (s *Server) EnableVersioning(bucket string) {
+	s.CreateBucket(bucket)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.versions[bucket] != nil {
+		return
+	}
+	s.versions[bucket] = map[string][]*version{}
+	for key, o := range s.buckets[bucket] {
+		s.versions[bucket][key] = []*version{{id: "null", object: o, lastModified: o.lastModified}}
+	}
+}
+
+// PutObject stores an object, creating the bucket if needed
+func (s *Server) PutObject(bucket, key string, data []byte, contentType string) {
+	s.CreateBucket(bucket)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(bucket, key, newObject(data, contentType, nil))
+}
+
+// PutObjectWithHeader stores an object like PutObject, with the Content-Type, the
+// Cache-Control, Content-Disposition, Content-Encoding, Content-Language and Expires
+// headers and the X-Amz-Meta-* user metadata of header
+func (s *Server) PutObjectWithHeader(bucket, key string, data []byte, header http.Header) {
+	s.CreateBucket(bucket)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(bucket, key, newObjectFromHeader(data, header))
+}
+
+// store makes o the current object of the key, or deletes the key if o is nil. In versioned
+// buckets o is added as the latest version, or a delete marker, whose ID is returned.
+func (s *Server) store(bucket, key string, o *object) string {
+	if o == nil {
+		delete(s.buckets[bucket], key)
+	} else {
+		s.buckets[bucket][key] = o
+	}
+
+	keys, ok := s.versions[bucket]
+	if !ok {
+		return ""
+	}
+
+	s.nextID++
+	v := &version{id: "v" + strconv.Itoa(s.nextID), object: o, lastModified: time.Now().UTC().Truncate(time.Second)}
+	if o != nil {
+		o.versionID = v.id
+		v.lastModified = o.lastModified
+	}
+	keys[key] = append([]*version{v}, keys[key]...)
+	return v.id
+}
+
+// Object returns the data of an object and whether it exists
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	return o.data, true
+}
+
+// Keys returns the sorted keys of a bucket
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.buckets[bucket])
+}
+
+func newObject(data []byte, contentType string, metadata map[string]string) *object {
+	sum := md5.Sum(data)
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+
+	return &object{
+		data:         data,
+		contentType:  contentType,
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: time.Now().UTC().Truncate(time.Second),
+		metadata:     metadata,
+	}
+}
+
+func newObjectFromHeader(data []byte, header http.Header) *object {
+	o := newObject(data, header.Get("Content-Type"), metadata(header))
+	o.header = http.Header{}
+	for _, name := range storedHeaders {
+		if v := header.Get(name); v != "" {
+			o.header.Set(name, v)
+		}
+	}
+	return o
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	if key != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) && !r.URL.Query().Has("tagging") {
+		// objects are never modified, only replaced, so the body is written without the lock
+		// and a client that doesn't read it doesn't block the other requests
+		s.mu.Lock()
+		objects, ok := s.buckets[bucket]
+		o := objects[key]
+		s.mu.Unlock()
+
+		if !ok {
+			writeError(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+			return
+		}
+		getObject(w, r, o)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects, ok := s.buckets[bucket]
+	if !ok && !(key == "" && r.Method == http.MethodPut) {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodPut:
+		if !ok {
+			s.buckets[bucket] = map[string]*object{}
+		}
+	case key == "" && r.Method == http.MethodHead:
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Has("versions"):
+		s.listVersions(w, r, bucket)
+	case key == "" && r.Method == http.MethodGet:
+		s.listObjects(w, r, bucket, objects)
+	case key == "" && r.Method == http.MethodPost && r.URL.Query().Has("delete"):
+		s.deleteObjects(w, r, bucket)
+	case key == "":
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "s3test doesn't support this request")
+	case r.Method == http.MethodPost && r.URL.Query().Has("uploads"):
+		s.createUpload(w, r, bucket, key)
+	case r.URL.Query().Has("tagging"):
+		s.serveTagging(w, r, bucket, key)
+	case r.URL.Query().Has("uploadId"):
+		s.serveUpload(w, r)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copyObject(w, r, bucket, key)
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, bucket, key)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, r, bucket, key)
+	default:
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "s3test doesn't support this request")
+	}
+}
+
+func getObject(w http.ResponseWriter, r *http.Request, o *object) {
+	if o == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	if m := r.Header.Get("If-Match"); m != "" && m != o.etag {
+		writeError(w, r, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+		return
+	}
+
+	h := w.Header()
+	h.Set("ETag", o.etag)
+	h.Set("Last-Modified", o.lastModified.Format(http.TimeFormat))
+	h.Set("Content-Type", o.contentType)
+	h.Set("Accept-Ranges", "bytes")
+	if o.versionID != "" {
+		h.Set("X-Amz-Version-Id", o.versionID)
+	}
+	for name, value := range o.metadata {
+		h.Set("X-Amz-Meta-"+name, value)
+	}
+	for name, values := range o.header {
+		h[name] = values
+	}
+
+	if m := r.Header.Get("If-None-Match"); m != "" && m == o.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	start, end := int64(0), int64(len(o.data))-1
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" {
+		var ok bool
+		start, end, ok = parseRange(rng, int64(len(o.data)))
+		if !ok {
+			writeError(w, r, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable")
+			return
+		}
+		h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(o.data)))
+		status = http.StatusPartialContent
+	}
+
+	h.Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(o.data[start : end+1])
+	}
+}
+
+// parseRange parses a single range "bytes=start-end", "bytes=start-" or "bytes=-suffix"
+func parseRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+
+	startText, endText, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+
+	if startText == "" {
+		suffix, err := strconv.ParseInt(endText, 10, 64)
+		if err != nil || suffix <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		return max(size-suffix, 0), size - 1, true
+	}
+
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+	if endText != "" {
+		if end, err = strconv.ParseInt(endText, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+	}
+	return start, min(end, size-1), true
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	o := newObjectFromHeader(data, r.Header)
+	if id := s.store(bucket, key, o); id != "" {
+		w.Header().Set("X-Amz-Version-Id", id)
+	}
+	w.Header().Set("ETag", o.etag)
+}
+
+// deleteObject deletes the key, which adds a delete marker in versioned buckets, or with
+// versionId removes the version, making the version after it the current object
+func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	id := r.URL.Query().Get("versionId")
+	if id == "" {
+		if id := s.store(bucket, key, nil); id != "" {
+			w.Header().Set("X-Amz-Delete-Marker", "true")
+			w.Header().Set("X-Amz-Version-Id", id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	versions := s.versions[bucket][key]
+	for i, v := range versions {
+		if v.id != id {
+			continue
+		}
+
+		versions = append(versions[:i:i], versions[i+1:]...)
+		s.versions[bucket][key] = versions
+		if i == 0 {
+			if len(versions) > 0 && versions[0].object != nil {
+				s.buckets[bucket][key] = versions[0].object
+			} else {
+				delete(s.buckets[bucket], key)
+			}
+		}
+		if len(versions) == 0 {
+			delete(s.versions[bucket], key)
+		}
+
+		if v.object == nil {
+			w.Header().Set("X-Amz-Delete-Marker", "true")
+		}
+		w.Header().Set("X-Amz-Version-Id", id)
+		break
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) copyObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	source, err := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+
+	srcBucket, srcKey, _ := strings.Cut(source, "/")
+	src, ok := s.buckets[srcBucket][srcKey]
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	if m := r.Header.Get("X-Amz-Copy-Source-If-Match"); m != "" && m != src.etag {
+		writeError(w, r, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+		return
+	}
+
+	o := newObject(src.data, src.contentType, src.metadata)
+	o.header = src.header
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		o = newObjectFromHeader(src.data, r.Header)
+	}
+	o.tags = src.tags
+	s.store(bucket, key, o)
+
+	writeXML(w, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		ETag         string
+		LastModified string
+	}{ETag: o.etag, LastModified: o.lastModified.Format(time.RFC3339)})
+}
+
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.uploads[id] = &upload{bucket: bucket, key: key, header: r.Header.Clone(), parts: map[int][]byte{}}
+
+	writeXML(w, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string
+		Key      string
+		UploadId string
+	}{Bucket: bucket, Key: key, UploadId: id})
+}
+
+// minPartSize is the minimum size of every part of a multipart upload but the last
+const minPartSize = 5 << 20
+
+// serveUpload handles UploadPart, UploadPartCopy, CompleteMultipartUpload and
+// AbortMultipartUpload
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("uploadId")
+	u, ok := s.uploads[id]
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+			return
+		}
+		data, ok := s.copyPart(w, r)
+		if !ok {
+			return
+		}
+		u.parts[number] = data
+		sum := md5.Sum(data)
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyPartResult"`
+			ETag         string
+			LastModified string
+		}{ETag: `"` + hex.EncodeToString(sum[:]) + `"`, LastModified: time.Now().UTC().Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		u.parts[number] = data
+		sum := md5.Sum(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	case r.Method == http.MethodPost:
+		var req struct {
+			Parts []struct {
+				PartNumber int
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+
+		var data []byte
+		for i, part := range req.Parts {
+			p, ok := u.parts[part.PartNumber]
+			if !ok {
+				writeError(w, r, http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found")
+				return
+			}
+			if len(p) < minPartSize && i < len(req.Parts)-1 {
+				writeError(w, r, http.StatusBadRequest, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size.")
+				return
+			}
+			data = append(data, p...)
+		}
+
+		o := newObjectFromHeader(data, u.header)
+		s.store(u.bucket, u.key, o)
+		delete(s.uploads, id)
+
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: u.bucket, Key: u.key, ETag: o.etag})
+	case r.Method == http.MethodDelete:
+		delete(s.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "s3test doesn't support this request")
+	}
+}
+
+// copyPart returns the bytes of the source of an UploadPartCopy in X-Amz-Copy-Source-Range
+func (s *Server) copyPart(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	source, err := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return nil, false
+	}
+
+	srcBucket, srcKey, _ := strings.Cut(source, "/")
+	src, ok := s.buckets[srcBucket][srcKey]
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return nil, false
+	}
+	if m := r.Header.Get("X-Amz-Copy-Source-If-Match"); m != "" && m != src.etag {
+		writeError(w, r, http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+		return nil, false
+	}
+
+	data := src.data
+	if rng := r.Header.Get("X-Amz-Copy-Source-Range"); rng != "" {
+		start, end, ok := parseRange(rng, int64(len(data)))
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "The x-amz-copy-source-range value must be of the form bytes=first-last")
+			return nil, false
+		}
+		data = data[start : end+1]
+	}
+	return data, true
+}
+
+// serveTagging handles GetObjectTagging and PutObjectTagging
+func (s *Server) serveTagging(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	o := s.buckets[bucket][key]
+	if o == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+
+	type tagging struct {
+		XMLName xml.Name `xml:"Tagging"`
+		TagSet  []tag    `xml:"TagSet>Tag"`
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeXML(w, tagging{TagSet: o.tags})
+	case http.MethodPut:
+		var req tagging
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		// objects are replaced rather than modified, see serveHTTP
+		tagged := *o
+		tagged.tags = req.TagSet
+		s.buckets[bucket][key] = &tagged
+		if versions := s.versions[bucket][key]; len(versions) > 0 {
+			versions[0].object = &tagged
+		}
+	default:
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "s3test doesn't support this request")
+	}
+}
+
+func (s *Server) deleteObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	var req struct {
+		Objects []struct {
+			Key string
+		} `xml:"Object"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	for _, o := range req.Objects {
+		s.store(bucket, o.Key, nil)
+	}
+
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"DeleteResult"`
+	}{})
+}
+
+type listContents struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+}
+
+type listPrefix struct {
+	Prefix string
+}
+
+type listResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string
+	Prefix                string
+	Delimiter             string `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	KeyCount              int
+	MaxKeys               int
+	IsTruncated           bool
+	Contents              []listContents
+	CommonPrefixes        []listPrefix
+}
+
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request, bucket string, objects map[string]*object) {
+	q := r.URL.Query()
+	if q.Get("list-type") != "2" {
+		writeError(w, r, http.StatusNotImplemented, "NotImplemented", "s3test only supports ListObjectsV2")
+		return
+	}
+
+	result := listResult{
+		Name:              bucket,
+		Prefix:            q.Get("prefix"),
+		Delimiter:         q.Get("delimiter"),
+		StartAfter:        q.Get("start-after"),
+		ContinuationToken: q.Get("continuation-token"),
+		MaxKeys:           1000,
+	}
+	if v := q.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "invalid max-keys")
+			return
+		}
+		result.MaxKeys = min(n, 1000)
+	}
+
+	// the continuation token is the last key or common prefix of the previous page
+	after := result.StartAfter
+	if result.ContinuationToken != "" {
+		after = result.ContinuationToken
+	}
+
+	last := ""
+	for _, key := range sortedKeys(objects) {
+		if !strings.HasPrefix(key, result.Prefix) || key <= after {
+			continue
+		}
+		if result.Delimiter != "" && strings.HasSuffix(after, result.Delimiter) && strings.HasPrefix(key, after) {
+			// under the common prefix that ended the previous page
+			continue
+		}
+
+		item := key
+		if result.Delimiter != "" {
+			if i := strings.Index(key[len(result.Prefix):], result.Delimiter); i >= 0 {
+				item = key[:len(result.Prefix)+i+len(result.Delimiter)]
+			}
+		}
+		if item == last {
+			continue
+		}
+
+		if result.KeyCount == result.MaxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = last
+			break
+		}
+
+		if item == key {
+			o := objects[key]
+			result.Contents = append(result.Contents, listContents{
+				Key:          key,
+				LastModified: o.lastModified.Format(time.RFC3339),
+				ETag:         o.etag,
+				Size:         int64(len(o.data)),
+				StorageClass: "STANDARD",
+			})
+		} else {
+			result.CommonPrefixes = append(result.CommonPrefixes, listPrefix{Prefix: item})
+		}
+		result.KeyCount++
+		last = item
+	}
+
+	writeXML(w, result)
+}
+
+type listVersion struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+}
+
+type listDeleteMarker struct {
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+}
+
+type listVersionsResult struct {
+	XMLName             xml.Name `xml:"ListVersionsResult"`
+	Name                string
+	Prefix              string
+	Delimiter           string `xml:",omitempty"`
+	KeyMarker           string
+	VersionIdMarker     string
+	NextKeyMarker       string `xml:",omitempty"`
+	NextVersionIdMarker string `xml:",omitempty"`
+	MaxKeys             int
+	IsTruncated         bool
+	Versions            []listVersion      `xml:"Version"`
+	DeleteMarkers       []listDeleteMarker `xml:"DeleteMarker"`
+	CommonPrefixes      []listPrefix
+}
+
+// listVersions handles ListObjectVersions. The objects of buckets that aren't versioned are
+// listed as versions with the ID "null".
+func (s *Server) listVersions(w http.ResponseWriter, r *http.Request, bucket string) {
+	q := r.URL.Query()
+	result := listVersionsResult{
+		Name:            bucket,
+		Prefix:          q.Get("prefix"),
+		Delimiter:       q.Get("delimiter"),
+		KeyMarker:       q.Get("key-marker"),
+		VersionIdMarker: q.Get("version-id-marker"),
+		MaxKeys:         1000,
+	}
+	if v := q.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, http.StatusBadRequest, "InvalidArgument", "invalid max-keys")
+			return
+		}
+		result.MaxKeys = min(n, 1000)
+	}
+
+	versions := s.versions[bucket]
+	if versions == nil {
+		versions = map[string][]*version{}
+		for key, o := range s.buckets[bucket] {
+			versions[key] = []*version{{id: "null", object: o, lastModified: o.lastModified}}
+		}
+	}
+	keys := make([]string, 0, len(versions))
+	for key := range versions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// the markers are the key and version ID of the last entry of the previous page
+	after, afterID := result.KeyMarker, result.VersionIdMarker
+	count, last, lastID := 0, "", ""
+	for _, key := range keys {
+		if !strings.HasPrefix(key, result.Prefix) || key < after || key == after && afterID == "" {
+			continue
+		}
+		if result.Delimiter != "" && strings.HasSuffix(after, result.Delimiter) && strings.HasPrefix(key, after) {
+			// under the common prefix that ended the previous page
+			continue
+		}
+
+		item := key
+		if result.Delimiter != "" {
+			if i := strings.Index(key[len(result.Prefix):], result.Delimiter); i >= 0 {
+				item = key[:len(result.Prefix)+i+len(result.Delimiter)]
+			}
+		}
+		if item != key {
+			if item == last {
+				continue
+			}
+			if count == result.MaxKeys {
+				result.IsTruncated = true
+				result.NextKeyMarker, result.NextVersionIdMarker = last, lastID
+				break
+			}
+			result.CommonPrefixes = append(result.CommonPrefixes, listPrefix{Prefix: item})
+			count++
+			last, lastID = item, ""
+			continue
+		}
+
+		skipping := key == after
+		for i, v := range versions[key] {
+			if skipping {
+				skipping = v.id != afterID
+				continue
+			}
+			if count == result.MaxKeys {
+				result.IsTruncated = true
+				result.NextKeyMarker, result.NextVersionIdMarker = last, lastID
+				break
+			}
+
+			modified := v.lastModified.Format(time.RFC3339)
+			if v.object == nil {
+				result.DeleteMarkers = append(result.DeleteMarkers, listDeleteMarker{
+					Key: key, VersionId: v.id, IsLatest: i == 0, LastModified: modified,
+				})
+			} else {
+				result.Versions = append(result.Versions, listVersion{
+					Key:          key,
+					VersionId:    v.id,
+					IsLatest:     i == 0,
+					LastModified: modified,
+					ETag:         v.object.etag,
+					Size:         int64(len(v.object.data)),
+					StorageClass: "STANDARD",
+				})
+			}
+			count++
+			last, lastID = key, v.id
+		}
+		if result.IsTruncated {
+			break
+		}
+	}
+
+	writeXML(w, result)
+}
+
+func sortedKeys(objects map[string]*object) []string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metadata returns the user metadata of the X-Amz-Meta-* headers
+func metadata(h http.Header) map[string]string {
+	meta := map[string]string{}
+	for name, values := range h {
+		if suffix, ok := strings.CutPrefix(name, "X-Amz-Meta-"); ok && len(values) > 0 {
+			meta[suffix] = values[0]
+		}
+	}
+	return meta
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
@@ -0,0 +1,128 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// SelectFormat is the format of an object queried with Select
+type SelectFormat string
+
+const (
+	// SelectCSV is CSV with a header line naming the columns. Rows are returned as CSV.
+	SelectCSV SelectFormat = "CSV"
+	// SelectJSON is JSON with one document per line. Rows are returned as JSON lines.
+	SelectJSON SelectFormat = "JSON"
+	// SelectParquet is Apache Parquet. Rows are returned as JSON lines.
+	SelectParquet SelectFormat = "Parquet"
+)
+
+// ErrSelectIncomplete is returned by the reader of Select if the response ended before S3
+// reported the end of the results
+var ErrSelectIncomplete = errors.New("s3fs: select results incomplete")
+
+// Select runs the SQL expression sqlExpr, such as "SELECT s.name FROM S3Object s WHERE
+// s.age > '30'", on the object with the name using SelectObjectContent and returns a reader
+// streaming the matching rows, so only the matches of a large object are downloaded. CSV and
+// JSON objects compressed with gzip or bzip2 are recognized by their .gz or .bz2 extension.
+// Objects stored by WithDeduplication are queried through their content.
+func (f FileSystem) Select(name, sqlExpr string, inputFormat SelectFormat) (io.ReadCloser, error) {
+	key, err := f.key(name)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.SelectObjectContentInput{
+		Bucket:         aws.String(f.bucket),
+		Key:            aws.String(key),
+		Expression:     aws.String(sqlExpr),
+		ExpressionType: aws.String(s3.ExpressionTypeSql),
+	}
+
+	compression := s3.CompressionTypeNone
+	switch path.Ext(key) {
+	case ".gz":
+		compression = s3.CompressionTypeGzip
+	case ".bz2":
+		compression = s3.CompressionTypeBzip2
+	}
+
+	switch inputFormat {
+	case SelectCSV:
+		input.InputSerialization = &s3.InputSerialization{
+			CSV:             &s3.CSVInput{FileHeaderInfo: aws.String(s3.FileHeaderInfoUse)},
+			CompressionType: aws.String(compression),
+		}
+		input.OutputSerialization = &s3.OutputSerialization{CSV: &s3.CSVOutput{}}
+	case SelectJSON:
+		input.InputSerialization = &s3.InputSerialization{
+			JSON:            &s3.JSONInput{Type: aws.String(s3.JSONTypeLines)},
+			CompressionType: aws.String(compression),
+		}
+		input.OutputSerialization = &s3.OutputSerialization{JSON: &s3.JSONOutput{}}
+	case SelectParquet:
+		input.InputSerialization = &s3.InputSerialization{Parquet: &s3.ParquetInput{}}
+		input.OutputSerialization = &s3.OutputSerialization{JSON: &s3.JSONOutput{}}
+	default:
+		return nil, errors.New("s3fs: unknown select format " + string(inputFormat))
+	}
+
+	// the content of a pointer object is queried rather than the pointer
+	ctx := context.Background()
+	source, _, err := f.headContent(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	input.Key = aws.String(source)
+
+	start := time.Now()
+	out, err := f.s3.SelectObjectContentWithContext(ctx, input)
+	f.requestDone(ctx, "SelectObjectContent", start, err, "key", key)
+	if err != nil {
+		return nil, toFSError(err)
+	}
+
+	pr, pw := io.Pipe()
+	go streamSelect(out.EventStream, pw)
+
+	return &selectReader{PipeReader: pr, stream: out.EventStream}, nil
+}
+
+// streamSelect writes the records of the event stream to pw
+func streamSelect(stream *s3.SelectObjectContentEventStream, pw *io.PipeWriter) {
+	ended := false
+	for event := range stream.Events() {
+		switch e := event.(type) {
+		case *s3.RecordsEvent:
+			if _, err := pw.Write(e.Payload); err != nil {
+				// the reader was closed
+				return
+			}
+		case *s3.EndEvent:
+			ended = true
+		}
+	}
+
+	err := stream.Err()
+	if err == nil && !ended {
+		err = ErrSelectIncomplete
+	}
+	pw.CloseWithError(err)
+}
+
+// selectReader reads the records of a Select and closes the event stream on Close
+type selectReader struct {
+	*io.PipeReader
+	stream *s3.SelectObjectContentEventStream
+}
+
+func (r *selectReader) Close() error {
+	r.PipeReader.Close()
+	return r.stream.Close()
+}
@@ -0,0 +1,60 @@
+package s3fs
+
+import (
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// fakeSelectReader replays events as a SelectObjectContentEventStreamReader
+type fakeSelectReader struct {
+	events chan s3.SelectObjectContentEventStreamEvent
+}
+
+func newFakeSelectReader(events ...s3.SelectObjectContentEventStreamEvent) *fakeSelectReader {
+	r := &fakeSelectReader{events: make(chan s3.SelectObjectContentEventStreamEvent, len(events))}
+	for _, e := range events {
+		r.events <- e
+	}
+	close(r.events)
+	return r
+}
+
+func (r *fakeSelectReader) Events() <-chan s3.SelectObjectContentEventStreamEvent { return r.events }
+func (r *fakeSelectReader) Close() error                                          { return nil }
+func (r *fakeSelectReader) Err() error                                            { return nil }
+
+func selectResult(events ...s3.SelectObjectContentEventStreamEvent) ([]byte, error) {
+	stream := s3.NewSelectObjectContentEventStream(func(es *s3.SelectObjectContentEventStream) {
+		es.Reader = newFakeSelectReader(events...)
+		es.StreamCloser = io.NopCloser(nil)
+	})
+
+	pr, pw := io.Pipe()
+	go streamSelect(stream, pw)
+
+	r := &selectReader{PipeReader: pr, stream: stream}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func TestStreamSelect(t *testing.T) {
+	data, err := selectResult(
+		&s3.RecordsEvent{Payload: []byte("1,Braund\n")},
+		&s3.StatsEvent{},
+		&s3.RecordsEvent{Payload: []byte("2,Cumings\n")},
+		&s3.EndEvent{},
+	)
+	if err != nil {
+		t.Fatalf("error: reading select results: %s", err)
+	}
+	if string(data) != "1,Braund\n2,Cumings\n" {
+		t.Fatalf("error: unexpected select results %q", data)
+	}
+
+	_, err = selectResult(&s3.RecordsEvent{Payload: []byte("1,Braund\n")})
+	if err != ErrSelectIncomplete {
+		t.Fatalf("error: expected ErrSelectIncomplete, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrObjectTooLarge is matched by the *SizeLimitError of objects over the size limit
+var ErrObjectTooLarge = errors.New("s3fs: object too large")
+
+// SizeLimitError is returned for objects larger than the limit of WithMaxObjectSize or
+// OpenLimited. Size is SizeUnknown if the object was read up to the limit without knowing
+// its size.
+type SizeLimitError struct {
+	Key   string
+	Size  int64
+	Limit int64
+}
+
+func (e *SizeLimitError) Error() string {
+	if e.Size == SizeUnknown {
+		return fmt.Sprintf("s3fs: %s is larger than the limit of %d bytes", e.Key, e.Limit)
+	}
+	return fmt.Sprintf("s3fs: %s has %d bytes, over the limit of %d bytes", e.Key, e.Size, e.Limit)
+}
+
+func (e *SizeLimitError) Is(target error) bool {
+	return target == ErrObjectTooLarge
+}
+
+// WithMaxObjectSize makes Open fail with *SizeLimitError for objects larger than n bytes,
+// before their body is read. The size of decompressed objects is only known at the end, so
+// they are read up to n bytes and the Read after that fails with *SizeLimitError instead.
+func WithMaxObjectSize(n int64) Option {
+	return func(f *FileSystem) {
+		f.maxObjectSize = n
+	}
+}
+
+// OpenLimited is like OpenContext with a size limit of limit bytes for this File only,
+// enforced like the limit of WithMaxObjectSize
+func (f FileSystem) OpenLimited(ctx context.Context, name string, limit int64) (http.File, error) {
+	f.maxObjectSize = limit
+	return f.OpenContext(ctx, name)
+}
+
+// checkSize returns a *SizeLimitError if the File is larger than the size limit
+func (f *File) checkSize() error {
+	limit := f.fs.maxObjectSize
+	if limit <= 0 || f.stat.size == SizeUnknown || f.stat.size <= limit {
+		return nil
+	}
+
+	return &SizeLimitError{Key: f.key, Size: f.stat.size, Limit: limit}
+}
+
+// limitStream limits p to the bytes of a File of unknown size left under the size limit. At
+// the limit it returns a *SizeLimitError, unless the File ends there.
+func (f *File) limitStream(p []byte) ([]byte, error) {
+	limit := f.fs.maxObjectSize
+	if limit <= 0 {
+		return p, nil
+	}
+
+	if f.offset >= limit {
+		var b [1]byte
+		if n, err := f.body.Read(b[:]); n == 0 && err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, &SizeLimitError{Key: f.key, Size: SizeUnknown, Limit: limit}
+	}
+
+	if remaining := limit - f.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	return p, nil
+}
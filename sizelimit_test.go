@@ -0,0 +1,66 @@
+package s3fs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMaxObjectSize(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv, WithMaxObjectSize(2000))
+
+	if _, err := s3Fs.Open("passengers.txt"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	_, err := s3Fs.Open("test_dataset_large.json")
+	var limitErr *SizeLimitError
+	if !errors.Is(err, ErrObjectTooLarge) || !errors.As(err, &limitErr) || limitErr.Size != 300<<10 {
+		t.Fatalf("error: expected a *SizeLimitError, got %v", err)
+	}
+
+	if _, err := newTestFileSystem(srv).OpenLimited(context.Background(), "passengers.txt", 100); !errors.Is(err, ErrObjectTooLarge) {
+		t.Fatalf("error: expected ErrObjectTooLarge, got %v", err)
+	}
+}
+
+func TestMaxObjectSizeDecompressed(t *testing.T) {
+	content := strings.Repeat("s3fs ", 1000)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(content))
+	zw.Close()
+
+	srv := newTestServer(t)
+	srv.PutObjectWithHeader("public-sample-data", "app.js", buf.Bytes(), http.Header{
+		"Content-Encoding": {"gzip"},
+	})
+
+	s3Fs := newTestFileSystem(srv, WithDecompression(), WithMaxObjectSize(1000))
+	f, err := s3Fs.Open("app.js")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if len(data) != 1000 || !errors.Is(err, ErrObjectTooLarge) {
+		t.Fatalf("error: expected 1000 bytes and ErrObjectTooLarge, got %d bytes, %v", len(data), err)
+	}
+
+	f, err = s3Fs.OpenLimited(context.Background(), "app.js", int64(len(content)))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	defer f.Close()
+
+	if data, err := io.ReadAll(f); err != nil || len(data) != len(content) {
+		t.Fatalf("error: expected the whole object at the limit, got %d bytes, %v", len(data), err)
+	}
+}
@@ -0,0 +1,167 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	defaultStagingTTL     = time.Minute
+	defaultStagingMaxSize = 8 << 20
+)
+
+// StagingOptions configures WithWriteStaging
+type StagingOptions struct {
+	// TTL is how long written data is kept if S3 doesn't confirm it, a minute if it is zero
+	TTL time.Duration
+	// MaxSize is the size of the largest object that is staged, 8MB if it is zero
+	MaxSize int64
+}
+
+// WithWriteStaging keeps the data written with WriteFile in memory until S3 serves it, so that
+// an Open or Stat right after a write never sees the previous version or a missing object, as
+// can happen with S3-compatible stores or caches in front of S3 that are only eventually
+// consistent. Open serves the staged data while GetObject answers with another ETag than the
+// one of the write or with a missing object, and forgets it once they match or after the TTL.
+// Staged data is shared by the copies of the FileSystem.
+func WithWriteStaging(opts StagingOptions) Option {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultStagingTTL
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultStagingMaxSize
+	}
+
+	return func(f *FileSystem) {
+		f.staging = &writeStaging{opts: opts, objects: map[string]*stagedObject{}}
+	}
+}
+
+// writeStaging holds the objects written recently. A nil *writeStaging stages nothing.
+type writeStaging struct {
+	opts StagingOptions
+
+	mu      sync.Mutex
+	objects map[string]*stagedObject
+}
+
+type stagedObject struct {
+	data        []byte
+	etag        string
+	contentType string
+	modTime     time.Time
+	expires     time.Time
+}
+
+// add stages data written as the object with the key
+func (s *writeStaging) add(key string, data []byte, etag, contentType string) {
+	if s == nil || int64(len(data)) > s.opts.MaxSize {
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = &stagedObject{
+		data:        data,
+		etag:        etag,
+		contentType: contentType,
+		modTime:     now,
+		expires:     now.Add(s.opts.TTL),
+	}
+}
+
+// get returns the object staged for the key, if it hasn't expired
+func (s *writeStaging) get(key string) *stagedObject {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.objects[key]
+	if o != nil && time.Now().After(o.expires) {
+		delete(s.objects, key)
+		return nil
+	}
+	return o
+}
+
+// drop forgets the objects staged for keys
+func (s *writeStaging) drop(keys ...string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.objects, key)
+	}
+}
+
+// evictStaged forgets the write staged for the key, for WithInvalidator
+func (f FileSystem) evictStaged(key string) {
+	f.staging.drop(key)
+}
+
+// stale reports whether the response of S3 for a staged object, with the etag or failing
+// with err, isn't the staged object yet. It forgets the staged object once S3 serves it.
+func (s *writeStaging) stale(key string, o *stagedObject, etag string, err error) bool {
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	if etag == o.etag {
+		s.drop(key)
+		return false
+	}
+	return true
+}
+
+// openStaged returns a File reading the staged object
+func (f FileSystem) openStaged(ctx context.Context, key string, o *stagedObject) *File {
+	size := int64(len(o.data))
+	return &File{
+		fs:          f,
+		ctx:         ctx,
+		key:         key,
+		body:        io.NopCloser(bytes.NewReader(o.data)),
+		stat:        fileStat{name: path.Base(key), size: size, totalSize: size, modTime: o.modTime},
+		contentType: o.contentType,
+		etag:        o.etag,
+		header:      http.Header{},
+		staged:      o.data,
+	}
+}
+
+// statStaged returns the FileInfo of a staged object if HeadObject doesn't return it yet
+func (f FileSystem) statStaged(key string, head *s3.HeadObjectOutput, err error) (os.FileInfo, bool) {
+	o := f.staging.get(key)
+	if o == nil {
+		return nil, false
+	}
+
+	var etag string
+	if head != nil {
+		etag = aws.StringValue(head.ETag)
+	}
+	if !f.staging.stale(key, o, etag, err) {
+		return nil, false
+	}
+
+	size := int64(len(o.data))
+	return fileStat{name: path.Base(key), size: size, totalSize: size, modTime: o.modTime}, true
+}
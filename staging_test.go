@@ -0,0 +1,91 @@
+package s3fs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteStaging(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv, WithWriteStaging(StagingOptions{}))
+
+	if err := s3Fs.WriteFile("passengers.txt", []byte("new passengers"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	// a stale read returns the previous version of the object
+	srv.PutObject("public-sample-data", "passengers.txt", []byte("old passengers"), "text/plain")
+
+	data, err := ReadFile(s3Fs, "passengers.txt")
+	if err != nil || string(data) != "new passengers" {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+	stat, err := s3Fs.Stat("passengers.txt")
+	if err != nil || stat.Size() != int64(len("new passengers")) {
+		t.Fatalf("error: expected the staged size, got %v, %v", stat, err)
+	}
+
+	f, _ := s3Fs.Open("passengers.txt")
+	defer f.Close()
+	b := make([]byte, 3)
+	if n, err := f.(*File).ReadAt(b, 4); err != nil || string(b[:n]) != "pas" {
+		t.Fatalf("error: ReadAt read %q, %v", b[:n], err)
+	}
+
+	if err := newTestFileSystem(srv).Remove("passengers.txt"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, err := ReadFile(s3Fs, "passengers.txt"); err != nil || string(data) != "new passengers" {
+		t.Fatalf("error: expected the staged object while it is missing, read %q, %v", data, err)
+	}
+
+	if err := s3Fs.Remove("passengers.txt"); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if _, err := s3Fs.Open("passengers.txt"); err == nil {
+		t.Fatalf("error: expected Remove to drop the staged object")
+	}
+}
+
+func TestWriteStagingConfirmed(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv, WithWriteStaging(StagingOptions{}))
+
+	if err := s3Fs.WriteFile("app.js", []byte("v2"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if data, err := ReadFile(s3Fs, "app.js"); err != nil || string(data) != "v2" {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+
+	// S3 served the write, so later versions come from S3
+	srv.PutObject("public-sample-data", "app.js", []byte("v3"), "text/javascript")
+	if data, err := ReadFile(s3Fs, "app.js"); err != nil || string(data) != "v3" {
+		t.Fatalf("error: expected the object of S3 once it served the write, read %q, %v", data, err)
+	}
+}
+
+func TestWriteStagingExpiry(t *testing.T) {
+	srv := newTestServer(t)
+	s3Fs := newTestFileSystem(srv, WithWriteStaging(StagingOptions{TTL: 10 * time.Millisecond, MaxSize: 4}))
+
+	if err := s3Fs.WriteFile("app.js", []byte("v2"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if err := s3Fs.WriteFile("large.js", []byte("large"), ""); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	srv.PutObject("public-sample-data", "app.js", []byte("v1"), "text/javascript")
+	srv.PutObject("public-sample-data", "large.js", []byte("small"), "text/javascript")
+
+	if data, _ := ReadFile(s3Fs, "large.js"); string(data) != "small" {
+		t.Fatalf("error: expected objects over MaxSize not to be staged, read %q", data)
+	}
+	if data, _ := ReadFile(s3Fs, "app.js"); string(data) != "v2" {
+		t.Fatalf("error: expected the staged object, read %q", data)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if data, _ := ReadFile(s3Fs, "app.js"); string(data) != "v1" {
+		t.Fatalf("error: expected the object of S3 after the TTL, read %q", data)
+	}
+}
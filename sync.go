@@ -0,0 +1,397 @@
+package s3fs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const defaultSyncConcurrency = 4
+
+// SyncOp is what a sync did with a file
+type SyncOp int
+
+const (
+	// SyncDownloaded is reported for objects downloaded by SyncDown
+	SyncDownloaded SyncOp = iota
+	// SyncUploaded is reported for files uploaded by SyncUp
+	SyncUploaded
+	// SyncDeleted is reported for files or objects deleted with SyncOptions.Delete
+	SyncDeleted
+	// SyncSkipped is reported for files and objects that didn't change
+	SyncSkipped
+)
+
+func (op SyncOp) String() string {
+	switch op {
+	case SyncDownloaded:
+		return "downloaded"
+	case SyncUploaded:
+		return "uploaded"
+	case SyncDeleted:
+		return "deleted"
+	case SyncSkipped:
+		return "skipped"
+	}
+	return "unknown"
+}
+
+// SyncEvent is passed to SyncOptions.Progress for every file a sync looked at
+type SyncEvent struct {
+	Op   SyncOp
+	Key  string
+	Path string
+	Size int64
+	// Err is set if the transfer or deletion failed; Op is what was attempted
+	Err error
+}
+
+// SyncOptions configures SyncDown and SyncUp
+type SyncOptions struct {
+	// Concurrency is the number of files transferred in parallel, 4 if it is zero
+	Concurrency int
+	// Delete removes the files or objects of the destination that aren't in the source
+	Delete bool
+	// Progress is called for every file, from several goroutines at once
+	Progress func(SyncEvent)
+}
+
+// md5ETag matches the ETags that are the MD5 of the object, which excludes multipart uploads
+var md5ETag = regexp.MustCompile(`^"?[0-9a-f]{32}"?$`)
+
+// SyncDown makes localDir a mirror of the objects under prefix, like "aws s3 sync". The key of
+// every object without the prefix is its path in localDir. Objects are downloaded when there's
+// no file for them, when the size differs, or when the ETag is the MD5 of the object and it
+// doesn't match the file. Otherwise they are downloaded if their LastModified differs from the
+// modification time of the file, which SyncDown sets to it. Objects are read like with Open,
+// so encrypted objects are decrypted and pointers of WithDeduplication are followed, but with
+// either option the listed sizes aren't those of the content and only modification times are
+// compared. Objects whose path would be outside localDir, such as keys with ".." segments,
+// are skipped and reported as SyncSkipped with an Err matching ErrInvalidName. The first error
+// stops the sync and is returned after the transfers in progress finish.
+func SyncDown(ctx context.Context, fsys *FileSystem, prefix, localDir string, opts SyncOptions) error {
+	raw := fsys.raw()
+	s := newSyncer(ctx, opts)
+
+	seen := map[string]bool{}
+	it := fsys.List(s.ctx, ListOptions{Prefix: prefix})
+	for {
+		object, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.fail(err)
+			break
+		}
+
+		rel := strings.TrimPrefix(object.Key, prefix)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			if opts.Progress != nil {
+				err := fmt.Errorf("%w: %s is outside the local directory", ErrInvalidName, object.Key)
+				opts.Progress(SyncEvent{Op: SyncSkipped, Key: object.Key, Size: object.Size, Err: err})
+			}
+			continue
+		}
+		local := filepath.Join(localDir, filepath.FromSlash(rel))
+		seen[local] = true
+
+		s.run(SyncEvent{Op: SyncDownloaded, Key: object.Key, Path: local, Size: object.Size}, func() (bool, error) {
+			if !syncChanged(local, object, raw.plainSizes(), false) {
+				return false, nil
+			}
+			return true, raw.download(s.ctx, object.Key, local)
+		})
+	}
+
+	// temporary files of the downloads in progress mustn't be deleted
+	s.wg.Wait()
+	if opts.Delete && s.ok() {
+		filepath.WalkDir(localDir, func(local string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || seen[local] {
+				return err
+			}
+			s.run(SyncEvent{Op: SyncDeleted, Path: local}, func() (bool, error) {
+				return true, os.Remove(local)
+			})
+			return nil
+		})
+	}
+
+	return s.wait()
+}
+
+// SyncUp makes the objects under prefix a mirror of localDir, the reverse of SyncDown. Files
+// are uploaded when there's no object for them, when the size differs, when the ETag is the
+// MD5 of the object and it doesn't match the file, or when the file is newer than the object.
+// Files are uploaded with Create, so the options of fsys such as WithEncryption apply.
+func SyncUp(ctx context.Context, fsys *FileSystem, localDir, prefix string, opts SyncOptions) error {
+	raw := fsys.raw()
+	s := newSyncer(ctx, opts)
+
+	remote := map[string]ObjectInfo{}
+	it := fsys.List(s.ctx, ListOptions{Prefix: prefix})
+	for {
+		object, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		remote[object.Key] = object
+	}
+
+	err := filepath.WalkDir(localDir, func(local string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !s.ok() {
+			return err
+		}
+
+		rel, err := filepath.Rel(localDir, local)
+		if err != nil {
+			return err
+		}
+		key := prefix + filepath.ToSlash(rel)
+
+		object, exists := remote[key]
+		delete(remote, key)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		s.run(SyncEvent{Op: SyncUploaded, Key: key, Path: local, Size: info.Size()}, func() (bool, error) {
+			if exists && !syncChanged(local, object, raw.plainSizes(), true) {
+				return false, nil
+			}
+			return true, raw.upload(key, local)
+		})
+		return nil
+	})
+	if err != nil {
+		s.fail(err)
+	}
+
+	if opts.Delete && s.ok() {
+		for key := range remote {
+			s.run(SyncEvent{Op: SyncDeleted, Key: key}, func() (bool, error) {
+				return true, raw.deleteObject(s.ctx, key)
+			})
+		}
+	}
+
+	return s.wait()
+}
+
+// raw returns a copy of f that uses names as keys, without normalizing them or falling back
+// to the index file or the SPA entry point
+func (f FileSystem) raw() FileSystem {
+	f.keyMapper = func(key string) (string, error) {
+		return key, nil
+	}
+	f.names = nil
+	f.indexFile = ""
+	f.spaFallback = false
+	return f
+}
+
+// plainSizes reports whether the sizes of listed objects are the sizes of their content,
+// which they aren't for encrypted objects and pointers
+func (f FileSystem) plainSizes() bool {
+	return f.encryption == nil && f.dedupPrefix == ""
+}
+
+// syncChanged reports whether the local file and the object differ
+func syncChanged(local string, object ObjectInfo, compareSize, up bool) bool {
+	info, err := os.Stat(local)
+	if err != nil {
+		return true
+	}
+
+	if compareSize {
+		if info.Size() != object.Size {
+			return true
+		}
+		if md5ETag.MatchString(object.ETag) {
+			sum, err := fileMD5(local)
+			return err != nil || sum != strings.Trim(object.ETag, `"`)
+		}
+	}
+
+	if up {
+		return info.ModTime().After(object.LastModified)
+	}
+	return !info.ModTime().Equal(object.LastModified)
+}
+
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// download writes the object with the key to local through a temporary file, so an
+// interrupted download doesn't leave a partial file
+func (f FileSystem) download(ctx context.Context, key, local string) error {
+	fi, err := f.openKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+
+	if fi.stat.isDir {
+		return errIsDir
+	}
+
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(local), "."+filepath.Base(local)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, fi); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), fi.stat.modTime, fi.stat.modTime); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), local)
+}
+
+// upload stores the file local as the object with the key
+func (f FileSystem) upload(key, local string) error {
+	file, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := f.Create(key)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, file); err != nil {
+		w.Abort()
+		return err
+	}
+
+	return w.Close()
+}
+
+// syncer runs the transfers of a sync with bounded concurrency
+type syncer struct {
+	parent   context.Context
+	ctx      context.Context
+	cancel   context.CancelFunc
+	progress func(SyncEvent)
+	sem      chan struct{}
+	wg       sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+func newSyncer(ctx context.Context, opts SyncOptions) *syncer {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultSyncConcurrency
+	}
+
+	syncCtx, cancel := context.WithCancel(ctx)
+	return &syncer{
+		parent:   ctx,
+		ctx:      syncCtx,
+		cancel:   cancel,
+		progress: opts.Progress,
+		sem:      make(chan struct{}, opts.Concurrency),
+	}
+}
+
+// run runs transfer in a goroutine once one is free and reports event, as SyncSkipped if
+// transfer didn't do anything
+func (s *syncer) run(event SyncEvent, transfer func() (bool, error)) {
+	select {
+	case s.sem <- struct{}{}:
+	case <-s.ctx.Done():
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.sem }()
+
+		done, err := transfer()
+		if !done && err == nil {
+			event.Op = SyncSkipped
+		}
+		event.Err = err
+		if s.progress != nil {
+			s.progress(event)
+		}
+		if err != nil {
+			s.fail(err)
+		}
+	}()
+}
+
+// fail records the first error and stops the sync
+func (s *syncer) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+		s.cancel()
+	}
+}
+
+func (s *syncer) ok() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err == nil
+}
+
+// wait waits for the transfers in progress and returns the first error
+func (s *syncer) wait() error {
+	s.wg.Wait()
+	s.cancel()
+
+	if s.err != nil {
+		return s.err
+	}
+	return s.parent.Err()
+}
@@ -0,0 +1,179 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/shijuleon/s3fs/s3test"
+)
+
+// syncOps records the operations reported by a sync
+type syncOps struct {
+	mu  sync.Mutex
+	ops map[string]SyncOp
+}
+
+func (s *syncOps) progress(event SyncEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops[event.Key+event.Path] = event.Op
+}
+
+func (s *syncOps) count(op SyncOp) int {
+	n := 0
+	for _, o := range s.ops {
+		if o == op {
+			n++
+		}
+	}
+	return n
+}
+
+func syncDown(t *testing.T, s3Fs *FileSystem, dir string, delete bool) *syncOps {
+	ops := &syncOps{ops: map[string]SyncOp{}}
+	err := SyncDown(context.Background(), s3Fs, "site/", dir, SyncOptions{Delete: delete, Progress: ops.progress})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	return ops
+}
+
+func TestSyncDown(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+	srv.CreateBucket("public-sample-data")
+	srv.PutObject("public-sample-data", "site/index.html", []byte("<h1>Home</h1>"), "text/html")
+	srv.PutObject("public-sample-data", "site/docs/setup.html", []byte("<h1>Setup</h1>"), "text/html")
+	srv.PutObject("public-sample-data", "other.txt", []byte("other"), "")
+	s3Fs := newTestFileSystem(srv)
+	dir := t.TempDir()
+
+	if ops := syncDown(t, s3Fs, dir, false); ops.count(SyncDownloaded) != 2 {
+		t.Fatalf("error: unexpected operations %v", ops.ops)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "docs", "setup.html"))
+	if err != nil || string(data) != "<h1>Setup</h1>" {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+
+	if ops := syncDown(t, s3Fs, dir, false); ops.count(SyncSkipped) != 2 {
+		t.Fatalf("error: expected unchanged objects to be skipped, got %v", ops.ops)
+	}
+
+	srv.PutObject("public-sample-data", "site/index.html", []byte("<h1>Welcome</h1>"), "text/html")
+	os.WriteFile(filepath.Join(dir, "stale.html"), []byte("stale"), 0644)
+	ops := syncDown(t, s3Fs, dir, true)
+	if ops.count(SyncDownloaded) != 1 || ops.count(SyncDeleted) != 1 {
+		t.Fatalf("error: unexpected operations %v", ops.ops)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale.html")); !os.IsNotExist(err) {
+		t.Fatalf("error: expected stale.html to be deleted, got %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "index.html"))
+	if string(data) != "<h1>Welcome</h1>" {
+		t.Fatalf("error: read %q", data)
+	}
+}
+
+func TestSyncDownOutsideDir(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+	srv.PutObject("public-sample-data", "site/index.html", []byte("<h1>Home</h1>"), "text/html")
+	srv.PutObject("public-sample-data", "site/../../escape.txt", []byte("escape"), "")
+	srv.PutObject("public-sample-data", "site/docs/../../../up.txt", []byte("up"), "")
+	s3Fs := newTestFileSystem(srv)
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "a", "b")
+	var mu sync.Mutex
+	var rejected []string
+	err := SyncDown(context.Background(), s3Fs, "site/", dir, SyncOptions{Progress: func(event SyncEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if errors.Is(event.Err, ErrInvalidName) && event.Op == SyncSkipped {
+			rejected = append(rejected, event.Key)
+		}
+	}})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if len(rejected) != 2 {
+		t.Fatalf("error: expected both keys outside the directory to be skipped, got %v", rejected)
+	}
+
+	for _, name := range []string{filepath.Join(parent, "escape.txt"), filepath.Join(parent, "up.txt")} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Fatalf("error: expected nothing written at %s, got %v", name, err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "index.html")); err != nil || string(data) != "<h1>Home</h1>" {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+}
+
+func TestSyncUp(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+	srv.CreateBucket("public-sample-data")
+	srv.PutObject("public-sample-data", "site/removed.html", []byte("removed"), "text/html")
+	s3Fs := newTestFileSystem(srv)
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>Home</h1>"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "setup.html"), []byte("<h1>Setup</h1>"), 0644)
+
+	ops := &syncOps{ops: map[string]SyncOp{}}
+	opts := SyncOptions{Delete: true, Progress: ops.progress}
+	if err := SyncUp(context.Background(), s3Fs, dir, "site/", opts); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if ops.count(SyncUploaded) != 2 || ops.count(SyncDeleted) != 1 {
+		t.Fatalf("error: unexpected operations %v", ops.ops)
+	}
+	if data, ok := srv.Object("public-sample-data", "site/docs/setup.html"); !ok || string(data) != "<h1>Setup</h1>" {
+		t.Fatalf("error: unexpected object %q", data)
+	}
+	if _, ok := srv.Object("public-sample-data", "site/removed.html"); ok {
+		t.Fatalf("error: expected site/removed.html to be deleted")
+	}
+
+	ops.ops = map[string]SyncOp{}
+	if err := SyncUp(context.Background(), s3Fs, dir, "site/", opts); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if ops.count(SyncSkipped) != 2 {
+		t.Fatalf("error: expected unchanged files to be skipped, got %v", ops.ops)
+	}
+}
+
+func TestSyncNameNormalization(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+	srv.PutObject("public-sample-data", "site/Docs/Setup.html", []byte("<h1>Setup</h1>"), "text/html")
+	s3Fs := newTestFileSystem(srv, WithNameNormalization(NameOptions{Strict: true, FoldCase: true}), WithSPAFallback())
+
+	dir := t.TempDir()
+	if ops := syncDown(t, s3Fs, dir, false); ops.count(SyncDownloaded) != 1 {
+		t.Fatalf("error: unexpected operations %v", ops.ops)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Docs", "Setup.html")); err != nil || string(data) != "<h1>Setup</h1>" {
+		t.Fatalf("error: read %q, %v", data, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "Docs", "Install.html"), []byte("<h1>Install</h1>"), 0644)
+	ops := &syncOps{ops: map[string]SyncOp{}}
+	if err := SyncUp(context.Background(), s3Fs, dir, "site/", SyncOptions{Delete: true, Progress: ops.progress}); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	if ops.count(SyncUploaded) != 1 || ops.count(SyncSkipped) != 1 || ops.count(SyncDeleted) != 0 {
+		t.Fatalf("error: unexpected operations %v", ops.ops)
+	}
+	if data, ok := srv.Object("public-sample-data", "site/Docs/Install.html"); !ok || string(data) != "<h1>Install</h1>" {
+		t.Fatalf("error: expected the key of the file to be kept, got keys %v", srv.Keys("public-sample-data"))
+	}
+}
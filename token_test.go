@@ -0,0 +1,92 @@
+package s3fs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequireToken(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	var gotRange string
+	handler := RequireToken(secret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+	}))
+
+	valid := NewToken(secret, "/videos/intro.mp4", time.Now().Add(time.Hour), "")
+	ranged := NewToken(secret, "/videos/intro.mp4", time.Now().Add(time.Hour), "0-1023")
+	expired := NewToken(secret, "/videos/intro.mp4", time.Now().Add(-time.Minute), "")
+	forged := NewToken([]byte("guess"), "/videos/intro.mp4", time.Now().Add(time.Hour), "")
+
+	cases := []struct {
+		description string
+		path        string
+		token       string
+		cookie      bool
+		rangeHeader string
+		code        int
+		wantRange   string
+	}{
+		{"valid token", "/videos/intro.mp4", valid, false, "", http.StatusOK, ""},
+		{"valid cookie", "/videos/intro.mp4", valid, true, "bytes=5000-", http.StatusOK, "bytes=5000-"},
+		{"missing token", "/videos/intro.mp4", "", false, "", http.StatusForbidden, ""},
+		{"other path", "/videos/secret.mp4", valid, false, "", http.StatusForbidden, ""},
+		{"expired token", "/videos/intro.mp4", expired, false, "", http.StatusForbidden, ""},
+		{"forged token", "/videos/intro.mp4", forged, false, "", http.StatusForbidden, ""},
+		{"range applied", "/videos/intro.mp4", ranged, false, "", http.StatusOK, "bytes=0-1023"},
+		{"range inside", "/videos/intro.mp4", ranged, false, "bytes=0-99,512-1023", http.StatusOK, "bytes=0-99,512-1023"},
+		{"range outside", "/videos/intro.mp4", ranged, false, "bytes=1000-2000", http.StatusForbidden, ""},
+		{"open range", "/videos/intro.mp4", ranged, false, "bytes=100-", http.StatusForbidden, ""},
+		{"suffix range", "/videos/intro.mp4", ranged, false, "bytes=-100", http.StatusForbidden, ""},
+	}
+
+	for _, c := range cases {
+		gotRange = ""
+		target := c.path
+		if !c.cookie && c.token != "" {
+			target += "?" + TokenParam + "=" + url.QueryEscape(c.token)
+		}
+
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if c.cookie {
+			r.AddCookie(&http.Cookie{Name: TokenCookie, Value: c.token})
+		}
+		if c.rangeHeader != "" {
+			r.Header.Set("Range", c.rangeHeader)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != c.code {
+			t.Fatalf("error: %s: got status %d, want %d", c.description, w.Code, c.code)
+		}
+		if gotRange != c.wantRange {
+			t.Fatalf("error: %s: got Range %q, want %q", c.description, gotRange, c.wantRange)
+		}
+	}
+}
+
+func TestRequireTokenIfRange(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	content := strings.NewReader(strings.Repeat("v", 4096))
+	handler := RequireToken(secret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"intro"`)
+		http.ServeContent(w, r, "intro.mp4", time.Time{}, content)
+	}))
+
+	ranged := NewToken(secret, "/videos/intro.mp4", time.Now().Add(time.Hour), "0-1023")
+	r := httptest.NewRequest(http.MethodGet, "/videos/intro.mp4?"+TokenParam+"="+url.QueryEscape(ranged), nil)
+	r.Header.Set("Range", "bytes=0-1023")
+	// a validator that doesn't match makes ServeContent ignore Range
+	r.Header.Set("If-Range", `"bogus"`)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.Len() != 1024 {
+		t.Fatalf("error: expected the range of the token, got status %d with %d bytes", w.Code, w.Body.Len())
+	}
+}
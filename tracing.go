@@ -0,0 +1,42 @@
+package s3fs
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/shijuleon/s3fs"
+
+// WithTracerProvider makes the FileSystem emit OpenTelemetry spans for every Open and every
+// S3 request, with the bucket, key, range and number of bytes read as attributes. Spans are
+// children of the span in the context passed to OpenContext or FileServer's request.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(f *FileSystem) {
+		f.tracer = tp.Tracer(tracerName)
+	}
+}
+
+var nopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// startSpan starts a span for an operation on key
+func (f FileSystem) startSpan(ctx context.Context, name, key string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("aws.s3.bucket", f.bucket),
+		attribute.String("aws.s3.key", key),
+	)
+
+	return f.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,36 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedBody(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	s3Fs := New("public-sample-data", "us-east-1", WithTracerProvider(tp))
+
+	_, span := s3Fs.startSpan(context.Background(), "s3.GetObject", "passengers.txt")
+	body := &objectBody{ctx: context.Background(), logger: nopLogger{}, ReadCloser: io.NopCloser(strings.NewReader("0123456789")), span: span}
+	io.Copy(io.Discard, body)
+	body.Close()
+	body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("error: %d spans ended, want 1", len(spans))
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range spans[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["aws.s3.bucket"] != "public-sample-data" || attrs["aws.s3.key"] != "passengers.txt" || attrs["s3fs.bytes"] != "10" {
+		t.Fatalf("error: unexpected span attributes %v", attrs)
+	}
+}
@@ -0,0 +1,85 @@
+package s3fs
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// TransportConfig tunes the HTTP transport used for S3 requests. Zero fields keep the values
+// of http.DefaultTransport. See WithTransport
+type TransportConfig struct {
+	// MaxIdleConns limits the idle connections kept for all hosts
+	MaxIdleConns int
+	// MaxIdleConnsPerHost limits the idle connections kept for the S3 endpoint. The default of
+	// http.DefaultTransport is 2, which makes a busy file server open a new TLS connection for
+	// most requests.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the connections to the S3 endpoint, including those in use
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept
+	IdleConnTimeout time.Duration
+	// TLSHandshakeTimeout limits the time spent on the TLS handshake
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout limits the time spent waiting for the headers of a response. Unlike
+	// http.Client.Timeout it doesn't limit the time spent reading the body of an object.
+	ResponseHeaderTimeout time.Duration
+	// DisableHTTP2 makes the transport only use HTTP/1.1
+	DisableHTTP2 bool
+}
+
+// WithHTTPClient sets the HTTP client used for S3 requests instead of the default of the AWS SDK
+func WithHTTPClient(c *http.Client) Option {
+	return func(f *FileSystem) {
+		f.config.HTTPClient = c
+	}
+}
+
+// WithEndpoint sends S3 requests to endpoint, such as "http://localhost:9000", instead of the
+// AWS endpoint of the region, with path-style addressing as expected by S3-compatible servers
+// like MinIO and LocalStack
+func WithEndpoint(endpoint string) Option {
+	return func(f *FileSystem) {
+		f.config.Endpoint = aws.String(endpoint)
+		f.config.S3ForcePathStyle = aws.Bool(true)
+	}
+}
+
+// WithTransport uses an HTTP client with a transport tuned with c for S3 requests
+func WithTransport(c TransportConfig) Option {
+	return func(f *FileSystem) {
+		f.config.HTTPClient = &http.Client{Transport: c.transport()}
+	}
+}
+
+// transport returns a copy of http.DefaultTransport with the settings of c
+func (c TransportConfig) transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if c.MaxIdleConns != 0 {
+		t.MaxIdleConns = c.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost != 0 {
+		t.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	}
+	if c.MaxConnsPerHost != 0 {
+		t.MaxConnsPerHost = c.MaxConnsPerHost
+	}
+	if c.IdleConnTimeout != 0 {
+		t.IdleConnTimeout = c.IdleConnTimeout
+	}
+	if c.TLSHandshakeTimeout != 0 {
+		t.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+	}
+	if c.ResponseHeaderTimeout != 0 {
+		t.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+	}
+	if c.DisableHTTP2 {
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return t
+}
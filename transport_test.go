@@ -0,0 +1,41 @@
+package s3fs
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestTransportConfig(t *testing.T) {
+	tr := TransportConfig{
+		MaxIdleConnsPerHost:   256,
+		ResponseHeaderTimeout: 5 * time.Second,
+		DisableHTTP2:          true,
+	}.transport()
+
+	def := http.DefaultTransport.(*http.Transport)
+	if tr.MaxIdleConnsPerHost != 256 || tr.ResponseHeaderTimeout != 5*time.Second {
+		t.Fatalf("error: settings weren't applied")
+	}
+	if tr.IdleConnTimeout != def.IdleConnTimeout || tr.MaxIdleConns != def.MaxIdleConns {
+		t.Fatalf("error: zero settings should keep the defaults")
+	}
+	if tr.ForceAttemptHTTP2 || tr.TLSNextProto == nil {
+		t.Fatalf("error: HTTP/2 wasn't disabled")
+	}
+
+	client := &http.Client{}
+	s3Fs := New("public-sample-data", "us-east-1", WithHTTPClient(client))
+	if s3Fs.s3.Config.HTTPClient != client {
+		t.Fatalf("error: the S3 client doesn't use the HTTP client")
+	}
+}
+
+func TestWithEndpoint(t *testing.T) {
+	s3Fs := New("bench", "us-east-1", WithEndpoint("http://localhost:9000"))
+	if aws.StringValue(s3Fs.s3.Config.Endpoint) != "http://localhost:9000" || !aws.BoolValue(s3Fs.s3.Config.S3ForcePathStyle) {
+		t.Fatalf("error: endpoint and path-style addressing weren't set")
+	}
+}
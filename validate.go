@@ -0,0 +1,103 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var (
+	// ErrBucketNotFound is returned by Validate when the bucket doesn't exist
+	ErrBucketNotFound = errors.New("s3fs: bucket not found")
+	// ErrNoCredentials is returned by Validate when no AWS credentials could be found
+	ErrNoCredentials = errors.New("s3fs: no credentials")
+	// ErrAccessDenied is returned by Validate when the credentials aren't allowed to read the bucket
+	ErrAccessDenied = errors.New("s3fs: access denied")
+)
+
+// ValidationError is returned by Validate. Kind is one of ErrBucketNotFound, ErrNoCredentials
+// or ErrAccessDenied, or nil if the failure didn't match any of them. Both Kind and Err can
+// be matched with errors.Is and errors.As.
+type ValidationError struct {
+	// Op is the S3 operation that failed
+	Op   string
+	Kind error
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Kind == nil {
+		return "s3fs: validating bucket: " + e.Op + ": " + e.Err.Error()
+	}
+	return e.Kind.Error() + ": " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Validate checks that the bucket exists and can be read with the configured credentials, so
+// that services can fail at startup rather than on the first request. It calls HeadBucket and
+// then probes read access with ListObjectsV2 and a HeadObject of the first key, if any.
+// HeadBucket is skipped for Object Lambda access points, which don't support it.
+// Errors are of type *ValidationError.
+func (f FileSystem) Validate(ctx context.Context) error {
+	if a, ok := parseBucketARN(f.bucket); !ok || !a.objectLambda() {
+		start := time.Now()
+		_, err := f.s3.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+			Bucket: aws.String(f.bucket),
+		})
+		f.requestDone(ctx, "HeadBucket", start, err)
+		if err != nil {
+			return newValidationError("HeadBucket", err)
+		}
+	}
+
+	list, err := f.listObjects(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucket),
+		MaxKeys: aws.Int64(1),
+	})
+	if err != nil {
+		return newValidationError("ListObjectsV2", err)
+	}
+
+	if len(list.Contents) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	_, err = f.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    list.Contents[0].Key,
+	})
+	f.requestDone(ctx, "HeadObject", start, err, "key", aws.StringValue(list.Contents[0].Key))
+	if err != nil {
+		return newValidationError("HeadObject", err)
+	}
+
+	return nil
+}
+
+func newValidationError(op string, err error) *ValidationError {
+	e := &ValidationError{Op: op, Err: err}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "NotFound", s3.ErrCodeNoSuchBucket:
+			e.Kind = ErrBucketNotFound
+		case "NoCredentialProviders":
+			e.Kind = ErrNoCredentials
+		case "Forbidden", "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken":
+			e.Kind = ErrAccessDenied
+		}
+	}
+
+	return e
+}
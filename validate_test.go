@@ -0,0 +1,39 @@
+package s3fs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestValidationError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{awserr.New("NotFound", "Not Found", nil), ErrBucketNotFound},
+		{awserr.New(s3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil), ErrBucketNotFound},
+		{awserr.New("NoCredentialProviders", "no valid providers in chain", nil), ErrNoCredentials},
+		{awserr.New("Forbidden", "Forbidden", nil), ErrAccessDenied},
+		{awserr.New("AccessDenied", "Access Denied", nil), ErrAccessDenied},
+	}
+
+	for _, c := range cases {
+		err := error(newValidationError("HeadBucket", c.err))
+		if !errors.Is(err, c.kind) {
+			t.Fatalf("error: %v is not %v", err, c.kind)
+		}
+
+		var aerr awserr.Error
+		if !errors.As(err, &aerr) || aerr != c.err {
+			t.Fatalf("error: %v doesn't wrap the S3 error", err)
+		}
+	}
+
+	err := newValidationError("HeadBucket", errors.New("connection reset"))
+	if err.Kind != nil {
+		t.Fatalf("error: unexpected kind %v", err.Kind)
+	}
+}
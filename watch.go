@@ -0,0 +1,162 @@
+package s3fs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+// WatchOp is the kind of change of a WatchEvent
+type WatchOp int
+
+const (
+	// WatchCreated is an object that didn't exist at the previous poll
+	WatchCreated WatchOp = iota + 1
+	// WatchModified is an object whose ETag changed since the previous poll
+	WatchModified
+	// WatchRemoved is an object that no longer exists
+	WatchRemoved
+)
+
+func (op WatchOp) String() string {
+	switch op {
+	case WatchCreated:
+		return "created"
+	case WatchModified:
+		return "modified"
+	case WatchRemoved:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// WatchEvent is a change to an object seen by Watch. Err is set instead when polling failed;
+// Watch keeps polling after an error and compares the next listing with the last good one.
+type WatchEvent struct {
+	Op   WatchOp
+	Key  string
+	ETag string
+	Size int64
+	Err  error
+}
+
+// watchedObject is what Watch remembers of an object between polls
+type watchedObject struct {
+	etag string
+	size int64
+}
+
+// Watch polls the keys starting with prefix every interval with ListObjectsV2 and sends an
+// event for every object created, modified (by ETag) or removed since the previous poll.
+// The prefix is a key prefix and isn't mapped by the KeyMapper. The first listing is the
+// baseline and produces no events. The channel is closed when ctx is done. An interval of 0
+// or less polls every 30 seconds.
+func (f FileSystem) Watch(ctx context.Context, prefix string, interval time.Duration) <-chan WatchEvent {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	events := make(chan WatchEvent)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last map[string]watchedObject
+		for {
+			objects, err := f.watchList(ctx, prefix)
+			switch {
+			case err != nil:
+				if ctx.Err() != nil {
+					return
+				}
+				if !sendEvent(ctx, events, WatchEvent{Key: prefix, Err: toFSError(err)}) {
+					return
+				}
+			case last == nil:
+				last = objects
+			default:
+				for _, event := range watchDiff(last, objects) {
+					if !sendEvent(ctx, events, event) {
+						return
+					}
+				}
+				last = objects
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+// watchList lists every key starting with prefix
+func (f FileSystem) watchList(ctx context.Context, prefix string) (map[string]watchedObject, error) {
+	objects := map[string]watchedObject{}
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(f.bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	for {
+		list, err := f.listObjects(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range list.Contents {
+			objects[aws.StringValue(object.Key)] = watchedObject{
+				etag: aws.StringValue(object.ETag),
+				size: aws.Int64Value(object.Size),
+			}
+		}
+
+		if !aws.BoolValue(list.IsTruncated) {
+			return objects, nil
+		}
+		input.ContinuationToken = list.NextContinuationToken
+	}
+}
+
+// watchDiff returns the events turning the listing before into after
+func watchDiff(before, after map[string]watchedObject) []WatchEvent {
+	events := []WatchEvent{}
+
+	for key, object := range after {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			events = append(events, WatchEvent{Op: WatchCreated, Key: key, ETag: object.etag, Size: object.size})
+		case old.etag != object.etag:
+			events = append(events, WatchEvent{Op: WatchModified, Key: key, ETag: object.etag, Size: object.size})
+		}
+	}
+
+	for key, object := range before {
+		if _, ok := after[key]; !ok {
+			events = append(events, WatchEvent{Op: WatchRemoved, Key: key, ETag: object.etag, Size: object.size})
+		}
+	}
+
+	return events
+}
+
+// sendEvent sends event unless ctx is done first
+func sendEvent(ctx context.Context, events chan<- WatchEvent, event WatchEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,48 @@
+package s3fs
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWatchDiff(t *testing.T) {
+	before := map[string]watchedObject{
+		"docs/a.html": {etag: `"1"`, size: 1},
+		"docs/b.html": {etag: `"2"`, size: 2},
+		"docs/c.html": {etag: `"3"`, size: 3},
+	}
+	after := map[string]watchedObject{
+		"docs/a.html": {etag: `"1"`, size: 1},
+		"docs/b.html": {etag: `"4"`, size: 4},
+		"docs/d.html": {etag: `"5"`, size: 5},
+	}
+
+	events := watchDiff(before, after)
+	sort.Slice(events, func(i, j int) bool { return events[i].Key < events[j].Key })
+
+	want := []WatchEvent{
+		{Op: WatchModified, Key: "docs/b.html", ETag: `"4"`, Size: 4},
+		{Op: WatchRemoved, Key: "docs/c.html", ETag: `"3"`, Size: 3},
+		{Op: WatchCreated, Key: "docs/d.html", ETag: `"5"`, Size: 5},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("error: expected %d events, got %v", len(want), events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("error: expected %v, got %v", want[i], events[i])
+		}
+	}
+}
+
+func TestWatchDefaultInterval(t *testing.T) {
+	s3Fs := newTestFileSystem(newTestServer(t))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	for event := range s3Fs.Watch(ctx, "", 0) {
+		t.Fatalf("error: unexpected event %+v", event)
+	}
+}